@@ -0,0 +1,325 @@
+package main
+
+import (
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/database"
+	"awesomeProject/internal/signing"
+	"awesomeProject/internal/user"
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+)
+
+// operatorID is the audit actor for changes made through these commands.
+var operatorID = uuid.Nil
+
+// migrateArgs is a parsed migrate subcommand.
+type migrateArgs struct {
+	sub   string
+	steps int
+}
+
+func parseMigrateArgs(args []string) (migrateArgs, error) {
+	if len(args) == 0 {
+		return migrateArgs{}, errors.New("usage: migrate up|down|status")
+	}
+	a := migrateArgs{sub: args[0], steps: 1}
+	fs := flag.NewFlagSet("migrate "+a.sub, flag.ContinueOnError)
+	switch a.sub {
+	case "down":
+		fs.IntVar(&a.steps, "steps", 1, "number of migrations to roll back")
+	case "up", "status":
+	default:
+		return migrateArgs{}, fmt.Errorf("unknown migrate command %q", a.sub)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return migrateArgs{}, err
+	}
+	if a.steps < 1 {
+		return migrateArgs{}, errors.New("-steps must be at least 1")
+	}
+	return a, nil
+}
+
+func migrateCommand(cfg *config.Config, args []string) error {
+	a, err := parseMigrateArgs(args)
+	if err != nil {
+		return err
+	}
+
+	pool, err := createDBPool(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	defer pool.Close()
+	migrations, err := database.Migrations()
+	if err != nil {
+		return err
+	}
+	m := database.NewMigrator(pool, migrations)
+	ctx := context.Background()
+
+	switch a.sub {
+	case "up":
+		done, err := m.Up(ctx)
+		for _, mig := range done {
+			fmt.Println("applied", mig)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Println("database is up to date")
+		}
+		return err
+	case "down":
+		done, err := m.Down(ctx, a.steps)
+		for _, mig := range done {
+			fmt.Println("rolled back", mig)
+		}
+		return err
+	default: // status
+		applied, err := m.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		appliedAt := make(map[int]string, len(applied))
+		for _, a := range applied {
+			appliedAt[a.Version] = a.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MIGRATION\tAPPLIED AT")
+		for _, mig := range migrations {
+			at, ok := appliedAt[mig.Version]
+			if !ok {
+				at = "pending"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", mig, at)
+		}
+		return w.Flush()
+	}
+}
+
+// userArgs is a parsed user subcommand.
+type userArgs struct {
+	sub                     string
+	name, email             string
+	activate, passwordStdin bool
+	// identifier is the ID, email or name of the user to act on. Every
+	// subcommand but create takes one.
+	identifier string
+}
+
+func parseUserArgs(args []string) (userArgs, error) {
+	if len(args) == 0 {
+		return userArgs{}, errors.New("usage: user create|activate|disable|enable|reset-password")
+	}
+	a := userArgs{sub: args[0]}
+	fs := flag.NewFlagSet("user "+a.sub, flag.ContinueOnError)
+	switch a.sub {
+	case "create":
+		fs.StringVar(&a.name, "name", "", "user name")
+		fs.StringVar(&a.email, "email", "", "email address")
+		fs.BoolVar(&a.activate, "activate", false, "activate without email verification")
+	case "reset-password":
+		fs.BoolVar(&a.passwordStdin, "password-stdin", false, "read the new password from stdin instead of mailing a reset link")
+	case "activate", "disable", "enable":
+	default:
+		return userArgs{}, fmt.Errorf("unknown user command %q", a.sub)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return userArgs{}, err
+	}
+	if a.sub == "create" {
+		if a.name == "" || a.email == "" {
+			return userArgs{}, errors.New("-name and -email are required")
+		}
+		return a, nil
+	}
+	if fs.NArg() != 1 {
+		return userArgs{}, fmt.Errorf("usage: user %s <id|email|name>", a.sub)
+	}
+	a.identifier = fs.Arg(0)
+	return a, nil
+}
+
+func userCommand(cfg *config.Config, args []string) error {
+	a, err := parseUserArgs(args)
+	if err != nil {
+		return err
+	}
+
+	pool, err := createDBPool(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	defer pool.Close()
+	logger := newLogger()
+	keys, _, err := loadSigningKeys(cfg.Signing, pool, logger)
+	if err != nil {
+		return err
+	}
+	service, err := newUserService(cfg, pool, keys, logger)
+	if err != nil {
+		return err
+	}
+	// Mails are sent in the background; let them finish before the pool
+	// is closed.
+	defer service.WaitForMails()
+	return runUserCommand(context.Background(), service, a, os.Stdin, os.Stdout)
+}
+
+// runUserCommand carries out a parsed user subcommand, reading passwords
+// from stdin.
+func runUserCommand(ctx context.Context, service user.Service, a userArgs, stdin io.Reader, stdout io.Writer) error {
+	if a.sub == "create" {
+		password, err := readPassword(stdin)
+		if err != nil {
+			return err
+		}
+		var u *user.User
+		if a.activate {
+			u, err = service.CreateActivatedUser(ctx, operatorID, a.name, a.email, password)
+		} else {
+			u, err = service.CreateNewUser(ctx, a.name, a.email, password)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, "created user", u.ID)
+		return nil
+	}
+
+	u, err := findUser(ctx, service, a.identifier)
+	if err != nil {
+		return err
+	}
+	switch a.sub {
+	case "activate":
+		err = service.SetActivated(ctx, operatorID, u.ID, true)
+	case "disable":
+		err = service.SetDisabled(ctx, operatorID, u.ID, true)
+	case "enable":
+		err = service.SetDisabled(ctx, operatorID, u.ID, false)
+	case "reset-password":
+		if !a.passwordStdin {
+			if u.Disabled {
+				return fmt.Errorf("user %s is disabled; enable it before mailing a reset link", u.ID)
+			}
+			err = service.ForgotPassword(ctx, u.Email)
+			if err == nil {
+				fmt.Fprintln(stdout, "mailed a password reset link to", u.Email)
+				return nil
+			}
+			break
+		}
+		var password string
+		password, err = readPassword(stdin)
+		if err == nil {
+			err = service.SetPassword(ctx, operatorID, u.ID, password)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s: done for user %s\n", a.sub, u.ID)
+	return nil
+}
+
+// readPassword reads a password from the first line of stdin, so it never
+// appears in the process list or shell history. It prompts when stdin is a
+// terminal.
+func readPassword(stdin io.Reader) (string, error) {
+	if f, ok := stdin.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(os.Stderr, "Password: ")
+		}
+	}
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("a password must be given on stdin")
+	}
+	return password, nil
+}
+
+// findUser looks up a user by ID, email or name.
+func findUser(ctx context.Context, service user.Service, identifier string) (*user.User, error) {
+	if id, err := uuid.Parse(identifier); err == nil {
+		return service.GetUserByID(ctx, id)
+	}
+	if strings.Contains(identifier, "@") {
+		return service.GetUserByEmail(ctx, identifier)
+	}
+	return service.GetUserByName(ctx, identifier)
+}
+
+// keysArgs is a parsed keys subcommand.
+type keysArgs struct {
+	emergency bool
+	kid       string
+}
+
+func parseKeysArgs(args []string) (keysArgs, error) {
+	if len(args) == 0 || args[0] != "rotate" {
+		return keysArgs{}, errors.New("usage: keys rotate [-emergency -kid id]")
+	}
+	var a keysArgs
+	fs := flag.NewFlagSet("keys rotate", flag.ContinueOnError)
+	fs.BoolVar(&a.emergency, "emergency", false, "retire the key immediately instead of after SIGN_RETIRE_AFTER")
+	fs.StringVar(&a.kid, "kid", "", "key to retire in an emergency rotation; defaults to the active key")
+	if err := fs.Parse(args[1:]); err != nil {
+		return keysArgs{}, err
+	}
+	return a, nil
+}
+
+func keysCommand(cfg *config.Config, args []string) error {
+	a, err := parseKeysArgs(args)
+	if err != nil {
+		return err
+	}
+
+	pool, err := createDBPool(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	defer pool.Close()
+	_, ring, err := loadSigningKeys(cfg.Signing, pool, newLogger())
+	if err != nil {
+		return err
+	}
+	return runKeysCommand(ring, a, os.Stdout)
+}
+
+// runKeysCommand rotates ring and lists its keys. ring is nil when keys come
+// from configuration instead.
+func runKeysCommand(ring *signing.KeyRing, a keysArgs, stdout io.Writer) error {
+	if ring == nil {
+		return errors.New("key rotation is not enabled; keys come from SIGN_KEY or SIGN_KEY_FILE")
+	}
+	var err error
+	if a.emergency {
+		err = ring.EmergencyRotate(a.kid)
+	} else {
+		err = ring.Rotate()
+	}
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALG\tSTATE\tCREATED AT")
+	for _, k := range ring.Keys() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.ID, k.Algorithm, k.State, k.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"awesomeProject/internal/mail"
+	"awesomeProject/internal/signing"
+	"awesomeProject/internal/user"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    migrateArgs
+		wantErr bool
+	}{
+		{name: "no command", args: nil, wantErr: true},
+		{name: "up", args: []string{"up"}, want: migrateArgs{sub: "up", steps: 1}},
+		{name: "down", args: []string{"down"}, want: migrateArgs{sub: "down", steps: 1}},
+		{name: "down steps", args: []string{"down", "-steps", "3"}, want: migrateArgs{sub: "down", steps: 3}},
+		{name: "down zero steps", args: []string{"down", "-steps", "0"}, wantErr: true},
+		{name: "steps on status", args: []string{"status", "-steps", "2"}, wantErr: true},
+		{name: "unknown command", args: []string{"sideways"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMigrateArgs(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseUserArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    userArgs
+		wantErr string
+	}{
+		{name: "no command", args: nil, wantErr: "usage: user"},
+		{name: "unknown command", args: []string{"rename", "bob"}, wantErr: `unknown user command "rename"`},
+		{
+			name: "create",
+			args: []string{"create", "-name", "bob", "-email", "bob@email.test", "-activate"},
+			want: userArgs{sub: "create", name: "bob", email: "bob@email.test", activate: true},
+		},
+		{name: "create without email", args: []string{"create", "-name", "bob"}, wantErr: "-name and -email are required"},
+		{name: "activate", args: []string{"activate", "bob"}, want: userArgs{sub: "activate", identifier: "bob"}},
+		{name: "disable without user", args: []string{"disable"}, wantErr: "usage: user disable <id|email|name>"},
+		{name: "enable two users", args: []string{"enable", "bob", "alice"}, wantErr: "usage: user enable <id|email|name>"},
+		{
+			name: "reset password from stdin",
+			args: []string{"reset-password", "-password-stdin", "bob"},
+			want: userArgs{sub: "reset-password", passwordStdin: true, identifier: "bob"},
+		},
+		{name: "flag of another command", args: []string{"activate", "-password-stdin", "bob"}, wantErr: "flag provided but not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUserArgs(tt.args)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseKeysArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    keysArgs
+		wantErr bool
+	}{
+		{name: "no command", args: nil, wantErr: true},
+		{name: "unknown command", args: []string{"list"}, wantErr: true},
+		{name: "rotate", args: []string{"rotate"}, want: keysArgs{}},
+		{name: "emergency", args: []string{"rotate", "-emergency", "-kid", "k1"}, want: keysArgs{emergency: true, kid: "k1"}},
+		{name: "unknown flag", args: []string{"rotate", "-now"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKeysArgs(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{name: "line", stdin: "secret password\nignored\n", want: "secret password"},
+		{name: "without newline", stdin: "secret", want: "secret"},
+		{name: "windows line ending", stdin: "secret\r\n", want: "secret"},
+		{name: "empty stdin", stdin: "", wantErr: true},
+		{name: "empty line", stdin: "\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readPassword(strings.NewReader(tt.stdin))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// outbox records the mails the service sends.
+type outbox struct {
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (o *outbox) Send(_ context.Context, m mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, m)
+	return nil
+}
+
+func (o *outbox) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.messages)
+}
+
+func newCommandTestService(t *testing.T) (*user.InMemoryService, *outbox) {
+	t.Helper()
+	box := &outbox{}
+	service, err := user.NewInMemoryUserService(user.NewInMemStore(),
+		user.WithSigningKeys(signing.NewStaticKeys(signing.NewHMACKey("test", []byte("secret")))),
+		user.WithMailer(box, "http://localhost/verify?token="),
+		user.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, box
+}
+
+func TestFindUser(t *testing.T) {
+	service, _ := newCommandTestService(t)
+	u, err := service.CreateActivatedUser(t.Context(), operatorID, "bob", "bob@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, identifier := range []string{u.ID.String(), "bob@email.test", "bob"} {
+		t.Run(identifier, func(t *testing.T) {
+			got, err := findUser(t.Context(), service, identifier)
+			if assert.NoError(t, err) {
+				assert.Equal(t, u.ID, got.ID)
+			}
+		})
+	}
+	t.Run("unknown", func(t *testing.T) {
+		_, err := findUser(t.Context(), service, "alice")
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestRunUserCommand(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("create", func(t *testing.T) {
+		service, _ := newCommandTestService(t)
+		var out strings.Builder
+		err := runUserCommand(ctx, service, userArgs{sub: "create", name: "bob", email: "bob@email.test", activate: true}, strings.NewReader("password\n"), &out)
+		if !assert.NoError(t, err) {
+			return
+		}
+		u, err := service.GetUserByName(ctx, "bob")
+		if assert.NoError(t, err) {
+			assert.True(t, u.Activated)
+			assert.Contains(t, out.String(), u.ID.String())
+		}
+	})
+
+	t.Run("create without password", func(t *testing.T) {
+		service, _ := newCommandTestService(t)
+		err := runUserCommand(ctx, service, userArgs{sub: "create", name: "bob", email: "bob@email.test"}, strings.NewReader(""), io.Discard)
+		assert.Error(t, err)
+		_, err = service.GetUserByName(ctx, "bob")
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+
+	t.Run("disable and enable", func(t *testing.T) {
+		service, _ := newCommandTestService(t)
+		u, err := service.CreateActivatedUser(ctx, operatorID, "bob", "bob@email.test", "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range []struct {
+			sub          string
+			wantDisabled bool
+		}{{"disable", true}, {"enable", false}} {
+			if err := runUserCommand(ctx, service, userArgs{sub: tt.sub, identifier: "bob"}, nil, io.Discard); err != nil {
+				t.Fatalf("%s: %v", tt.sub, err)
+			}
+			got, err := service.GetUserByID(ctx, u.ID)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.wantDisabled, got.Disabled, tt.sub)
+			}
+		}
+	})
+
+	t.Run("reset password from stdin", func(t *testing.T) {
+		service, _ := newCommandTestService(t)
+		if _, err := service.CreateActivatedUser(ctx, operatorID, "bob", "bob@email.test", "password"); err != nil {
+			t.Fatal(err)
+		}
+		err := runUserCommand(ctx, service, userArgs{sub: "reset-password", passwordStdin: true, identifier: "bob@email.test"}, strings.NewReader("newpassword\n"), io.Discard)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = service.Authenticate(ctx, "bob@email.test", "newpassword")
+		assert.NoError(t, err)
+	})
+
+	t.Run("reset password by mail", func(t *testing.T) {
+		service, box := newCommandTestService(t)
+		if _, err := service.CreateActivatedUser(ctx, operatorID, "bob", "bob@email.test", "password"); err != nil {
+			t.Fatal(err)
+		}
+		err := runUserCommand(ctx, service, userArgs{sub: "reset-password", identifier: "bob"}, nil, io.Discard)
+		assert.NoError(t, err)
+		service.WaitForMails()
+		assert.Equal(t, 1, box.count())
+	})
+
+	t.Run("reset password of a disabled user", func(t *testing.T) {
+		service, box := newCommandTestService(t)
+		u, err := service.CreateActivatedUser(ctx, operatorID, "bob", "bob@email.test", "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := service.SetDisabled(ctx, operatorID, u.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		err = runUserCommand(ctx, service, userArgs{sub: "reset-password", identifier: "bob"}, nil, io.Discard)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "is disabled")
+		}
+		service.WaitForMails()
+		assert.Equal(t, 0, box.count())
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		service, _ := newCommandTestService(t)
+		err := runUserCommand(ctx, service, userArgs{sub: "activate", identifier: "nobody"}, nil, io.Discard)
+		assert.ErrorIs(t, err, user.ErrNotFound)
+	})
+}
+
+func TestRunKeysCommand(t *testing.T) {
+	if err := runKeysCommand(nil, keysArgs{}, io.Discard); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Errorf("runKeysCommand() without a key ring error = %v", err)
+	}
+
+	ring := signing.NewKeyRing(signing.NewInMemKeyStore(), signing.ES256, time.Hour, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := ring.Load(); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := runKeysCommand(ring, keysArgs{}, &out); err != nil {
+		t.Fatalf("runKeysCommand() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 1+len(ring.Keys()))
+	assert.True(t, strings.HasPrefix(lines[0], "KID"))
+
+	err := runKeysCommand(ring, keysArgs{emergency: true, kid: "unknown"}, io.Discard)
+	assert.True(t, errors.Is(err, signing.ErrKeyNotFound), "runKeysCommand() error = %v", err)
+}
@@ -1,119 +1,139 @@
 package main
 
 import (
-	"awesomeProject/internal/database"
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/mail"
+	"awesomeProject/internal/signing"
+	"awesomeProject/internal/telemetry"
 	"awesomeProject/internal/user"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"log/slog"
-	"net/http"
 	"os"
+	"time"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/cors"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
-	openfga "github.com/openfga/go-sdk"
-	openfgaClient "github.com/openfga/go-sdk/client"
 )
 
+const usage = `Usage: api-server [-config file] <command> [arguments]
+
+Configuration is read from the optional YAML or JSON file given by -config or
+CONFIG_FILE, then .env, then the environment. Any variable can be read from a
+file instead by setting NAME_FILE.
+
+Commands:
+  serve                         run the HTTP server (default)
+  migrate up                    apply pending migrations
+  migrate down [-steps n]       roll back the latest n migrations (default 1)
+  migrate status                list applied and pending migrations
+  user create -name n -email e [-activate]
+                                create a user; the password is read from stdin
+  user activate <id|email|name>
+  user disable <id|email|name>  lock the user out and revoke all sessions
+  user enable <id|email|name>   undo disable
+  user reset-password [-password-stdin] <id|email|name>
+                                set the password read from stdin, or mail a
+                                reset link without -password-stdin
+  keys rotate [-emergency -kid id]
+                                rotate the signing keys
+`
+
 func main() {
-	err := godotenv.Load()
-	if err != nil {
-		panic(err)
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
+}
 
-	// Create database connection pool
-	pool, err := createDBPool()
-	if err != nil {
-		log.Fatalf("Failed to create database pool: %v", err)
+func run(args []string) error {
+	global := flag.NewFlagSet("api-server", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(global.Output(), usage) }
+	configFile := global.String("config", "", "YAML or JSON configuration file")
+	if err := global.Parse(args); err != nil {
+		return err
 	}
-	defer pool.Close()
 
-	// FGA setup
-	fgaClient, err := openfgaClient.NewSdkClient(&openfgaClient.ClientConfiguration{
-		ApiUrl:  os.Getenv("FGA_API_URL"),
-		StoreId: os.Getenv("FGA_STORE_ID"),
-	})
+	cmd, args := "serve", global.Args()
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	if cmd == "help" {
+		fmt.Print(usage)
+		return nil
+	}
 
+	cfg, err := config.Load(*configFile)
 	if err != nil {
-		log.Fatalf("Failed to create FGA client: %v", err)
+		return err
+	}
+	switch cmd {
+	case "serve":
+		return serve(cfg, args)
+	case "migrate":
+		return migrateCommand(cfg, args)
+	case "user":
+		return userCommand(cfg, args)
+	case "keys":
+		return keysCommand(cfg, args)
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
 	}
+}
+func newLogger() *slog.Logger {
+	textHandler := slog.NewTextHandler(os.Stdout, nil)
+	return slog.New(telemetry.NewLogHandler(textHandler))
+}
 
-	// Run migrations
-	ctx := context.Background()
-	if err := database.RunMigrations(ctx, pool); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+// loadSigningKeys returns the configured keys and, when rotation is enabled,
+// the key ring behind them.
+func loadSigningKeys(cfg signing.Config, pool *pgxpool.Pool, logger *slog.Logger) (signing.KeySource, *signing.KeyRing, error) {
+	keys, err := signing.New(cfg, signing.NewPostgresKeyStore(pool), logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load signing keys: %w", err)
 	}
+	ring, _ := keys.(*signing.KeyRing)
+	return keys, ring, nil
+}
 
-	// Logger
-	textHandler := slog.NewTextHandler(os.Stdout, nil)
-	logger := slog.New(textHandler)
+// newUserService wires the user service to Postgres the same way for the
+// server and the operator commands.
+func newUserService(cfg *config.Config, pool *pgxpool.Pool, keys signing.KeySource, logger *slog.Logger) (*user.InMemoryService, error) {
+	mailer, err := mail.New(cfg.Mail, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure mail sender: %w", err)
+	}
 
-	// Dependency Injection
 	// Use PostgresStore for database persistence
 	var store user.Store = user.NewPostgresStore(pool)
 	// For in-memory storage (old implementation), use:
 	// var store user.Store = user.NewInMemStore()
 
-	var service user.Service = user.NewInMemoryUserService(store)
-	var handler = user.Handler{Service: service, Logger: logger}
-
-	r := chi.NewRouter()
-
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	}))
-
-	// Health check route
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("OK"))
-	})
-
-	// User routes
-	r.Post("/user", ErrorHandler(handler.CreateUser))
-	r.Post("/authenticate", ErrorHandler(handler.Authenticate))
-	r.Get("/user", ErrorHandler(handler.SearchUser))
-	r.Get("/user/{id}", ErrorHandler(handler.GetUser))
-
-	// Authentication routes
-	r.Post("/authenticate", ErrorHandler(handler.Authenticate))
-
-	log.Println("Server starting on :4001")
-	err = http.ListenAndServe(":4000", r)
-	if err != nil {
-		panic(err)
-	}
-}
-
-func createDBPool() (*pgxpool.Pool, error) {
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-
-	// Build connection string
-	connString := fmt.Sprintf(
-		"postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		dbUser, dbPassword, dbHost, dbPort, dbName,
+	return user.NewInMemoryUserService(
+		store,
+		user.WithRefreshStore(user.NewPostgresRefreshStore(pool)),
+		user.WithSigningKeys(keys),
+		user.WithRevocationStore(user.NewCachedRevocationStore(user.NewPostgresRevocationStore(pool), 5*time.Second)),
+		user.WithActionTokenStore(user.NewPostgresActionTokenStore(pool)),
+		user.WithMailer(mailer, cfg.Accounts.VerifyURL),
+		user.WithPasswordResetURL(cfg.Accounts.ResetURL),
+		user.WithRequireActivation(cfg.Accounts.RequireActivation),
+		user.WithRolesURL(cfg.Accounts.RolesURL),
+		user.WithLogger(logger),
 	)
+}
 
+func createDBPool(db config.Database) (*pgxpool.Pool, error) {
 	// Configure pool
-	config, err := pgxpool.ParseConfig(connString)
+	poolConfig, err := pgxpool.ParseConfig(db.ConnString())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
+	poolConfig.ConnConfig.Tracer = telemetry.QueryTracer{}
 
 	// Create pool
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
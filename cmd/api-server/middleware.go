@@ -2,29 +2,52 @@ package main
 
 import (
 	"awesomeProject/internal/apperror"
-	"encoding/json"
+	"crypto/subtle"
 	"errors"
 	"net/http"
+	"strings"
 )
 
-type ErrorResponse struct {
-	Error string `json:"error"`
-}
-
 func ErrorHandler(fn func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := fn(w, r); err != nil {
-			var httpErr *apperror.HTTPError
-			if errors.As(err, &httpErr) {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(httpErr.StatusCode)
-				json.NewEncoder(w).Encode(ErrorResponse{Error: httpErr.Error()})
-				return
-			}
-			// Default to 500 for unknown errors
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(ErrorResponse{Error: "Internal Server Error"})
+			apperror.Write(w, r, err)
 		}
 	}
 }
+
+// RequireAPIKey guards operator endpoints with a static bearer key. An empty
+// key disables the routes entirely.
+func RequireAPIKey(key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			if key == "" {
+				return apperror.NotFound(errors.New("not found"))
+			}
+			got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(key)) != 1 {
+				return apperror.Unauthorized(errors.New("invalid API key")).WithCode(apperror.CodeAuthInvalidAPIKey)
+			}
+			next.ServeHTTP(w, r)
+			return nil
+		})
+	}
+}
+
+// RequireClientCredentials authenticates machine clients with HTTP Basic
+// credentials, as used by resource servers calling /introspect. Failures are
+// OAuth invalid_client errors.
+func RequireClientCredentials(clients map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return ErrorHandler(func(w http.ResponseWriter, r *http.Request) error {
+			id, secret, ok := r.BasicAuth()
+			want, known := clients[id]
+			if !ok || !known || subtle.ConstantTimeCompare([]byte(secret), []byte(want)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="introspection"`)
+				return apperror.InvalidClient(errors.New("invalid client credentials"))
+			}
+			next.ServeHTTP(w, r)
+			return nil
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"awesomeProject/internal/apperror"
+	"awesomeProject/internal/config"
+	"awesomeProject/internal/database"
+	"awesomeProject/internal/health"
+	"awesomeProject/internal/metrics"
+	"awesomeProject/internal/signing"
+	"awesomeProject/internal/telemetry"
+	"awesomeProject/internal/user"
+	"awesomeProject/pkg/authmw"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/cors"
+	openfgaClient "github.com/openfga/go-sdk/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func serve(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	logger := newLogger()
+	// apperror.Write logs server errors through the default logger.
+	slog.SetDefault(logger)
+
+	// SIGINT or SIGTERM starts a graceful shutdown; a second one kills the
+	// process as usual because stop restores the default handling.
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Tracing
+	shutdownTracing, err := telemetry.Setup(signalCtx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(context.Background())
+
+	// Create database connection pool
+	pool, err := createDBPool(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	defer pool.Close()
+
+	// FGA setup. The client gets its own transport so its calls are traced
+	// and its connections can be closed on shutdown.
+	fgaTransport := http.DefaultTransport.(*http.Transport).Clone()
+	defer fgaTransport.CloseIdleConnections()
+	fgaHTTP := &http.Client{
+		Transport: telemetry.Transport(fgaTransport),
+		Timeout:   10 * time.Second,
+	}
+	fgaClient, err := openfgaClient.NewSdkClient(&openfgaClient.ClientConfiguration{
+		ApiUrl:     cfg.FGA.APIURL,
+		StoreId:    cfg.FGA.StoreID,
+		HTTPClient: fgaHTTP,
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to create FGA client: %w", err)
+	}
+
+	// Run migrations
+	if err := database.RunMigrations(signalCtx, pool); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	// Background jobs get their own context so they are stopped only after
+	// the last request has drained.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	var jobs sync.WaitGroup
+	runJob := func(run func(ctx context.Context)) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			run(jobsCtx)
+		}()
+	}
+
+	// Signing keys
+	keys, ring, err := loadSigningKeys(cfg.Signing, pool, logger)
+	if err != nil {
+		return err
+	}
+	if ring != nil {
+		runJob(func(ctx context.Context) { ring.Run(ctx, time.Minute) })
+	}
+
+	// Dependency Injection
+	userService, err := newUserService(cfg, pool, keys, logger)
+	if err != nil {
+		return err
+	}
+	runJob(func(ctx context.Context) { userService.RunPurge(ctx, time.Hour) })
+	var service user.Service = user.NewTracedService(userService)
+	var handler = user.Handler{Service: service, Logger: logger}
+	var jwksHandler = signing.Handler{Keys: keys, Ring: ring}
+
+	r := chi.NewRouter()
+
+	r.Use(telemetry.Middleware)
+	r.Use(metrics.Middleware)
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins:   []string{"https://*", "http://*"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"Link"},
+		AllowCredentials: false,
+		MaxAge:           300, // Maximum value not ignored by any of major browsers
+	}))
+	r.NotFound(apperror.NotFoundHandler)
+	r.MethodNotAllowed(apperror.MethodNotAllowedHandler(r))
+
+	// Health routes. Liveness only shows the process is serving; readiness
+	// checks the dependencies and fails once shutdown starts so load
+	// balancers stop sending new requests. /health is kept for existing
+	// probes.
+	live := health.NewRegistry(health.WithLogger(logger))
+	ready := health.NewRegistry(
+		health.WithTimeout(cfg.Health.CheckTimeout),
+		health.WithCacheTTL(cfg.Health.CacheTTL),
+		health.WithLogger(logger),
+	)
+	ready.Register("postgres", pool.Ping, 0)
+	ready.Register("signing_key", func(ctx context.Context) error {
+		_, err := signing.SigningKeyContext(ctx, keys)
+		return err
+	}, 0)
+	if cfg.FGA.StoreID != "" {
+		ready.Register("fga", func(ctx context.Context) error {
+			_, err := fgaClient.GetStore(ctx).Execute()
+			return err
+		}, 0)
+	}
+	r.Method(http.MethodGet, "/livez", live)
+	r.Method(http.MethodGet, "/readyz", ready)
+	r.Method(http.MethodGet, "/health", ready)
+
+	// Metrics
+	prometheus.MustRegister(metrics.NewPoolCollector(pool))
+	r.Method(http.MethodGet, "/metrics", promhttp.Handler())
+
+	// User routes
+	r.Post("/user", ErrorHandler(handler.CreateUser))
+	r.Post("/user/verify", ErrorHandler(handler.VerifyUser))
+	r.Post("/user/verify/resend", ErrorHandler(handler.ResendVerification))
+	r.Get("/user", ErrorHandler(handler.SearchUser))
+	r.Get("/user/{id}", ErrorHandler(handler.GetUser))
+
+	// Authentication routes
+	r.Post("/authenticate", ErrorHandler(handler.Authenticate))
+	r.Post("/token/refresh", ErrorHandler(handler.RefreshToken))
+	r.Post("/token/revoke", ErrorHandler(handler.RevokeToken))
+	r.Post("/logout", ErrorHandler(handler.Logout))
+	r.Post("/password/forgot", ErrorHandler(handler.ForgotPassword))
+	r.Post("/password/reset", ErrorHandler(handler.ResetPassword))
+	r.Get("/.well-known/jwks.json", ErrorHandler(jwksHandler.JWKS))
+
+	// Authenticated routes
+	r.Group(func(r chi.Router) {
+		r.Use(authmw.Middleware(service))
+		r.Get("/me", ErrorHandler(handler.Me))
+		r.Put("/me/password", ErrorHandler(handler.ChangePassword))
+		r.Patch("/user/{id}", ErrorHandler(handler.UpdateUser))
+		r.Delete("/user/{id}", ErrorHandler(handler.DeleteUser))
+		r.With(authmw.RequireRole(user.AdminRole)).Post("/user/{id}/restore", ErrorHandler(handler.RestoreUser))
+		r.With(authmw.RequireRole(user.AdminRole)).Get("/users", ErrorHandler(handler.ListUsers))
+		r.With(authmw.RequireRole(user.AdminRole)).Get("/users/search", ErrorHandler(handler.FuzzySearchUsers))
+	})
+
+	// Resource server routes
+	introspectionClients, err := cfg.Clients()
+	if err != nil {
+		return err
+	}
+	r.Group(func(r chi.Router) {
+		r.Use(RequireClientCredentials(introspectionClients))
+		r.Post("/introspect", ErrorHandler(handler.Introspect))
+	})
+
+	// Operator routes
+	r.Group(func(r chi.Router) {
+		r.Use(RequireAPIKey(cfg.AdminAPIKey))
+		r.Post("/admin/keys/rotate", ErrorHandler(jwksHandler.Rotate))
+	})
+
+	srv := &http.Server{
+		Addr:              cfg.HTTP.Addr,
+		Handler:           r,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.Info("Server starting", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server stopped: %w", err)
+	case <-signalCtx.Done():
+	}
+	stop()
+
+	logger.Info("Shutting down", "delay", cfg.HTTP.ShutdownDelay, "timeout", cfg.HTTP.ShutdownTimeout)
+	ready.SetShuttingDown()
+	time.Sleep(cfg.HTTP.ShutdownDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	err = srv.Shutdown(shutdownCtx)
+	if err != nil {
+		logger.Error("Requests did not drain in time, closing connections", "error", err)
+		_ = srv.Close()
+	}
+	if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) {
+		logger.Error("Server stopped unexpectedly", "error", serveErr)
+	}
+
+	stopJobs()
+	jobs.Wait()
+	userService.WaitForMails()
+
+	// Close the database before the FGA client; the defers above only cover
+	// early returns.
+	pool.Close()
+	fgaTransport.CloseIdleConnections()
+
+	// Flush the spans of the drained requests last.
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		logger.Error("Failed to flush traces", "error", err)
+	}
+	logger.Info("Shutdown complete")
+	return nil
+}
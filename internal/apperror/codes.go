@@ -0,0 +1,78 @@
+package apperror
+
+import "net/http"
+
+// Code is a stable, machine-readable error identifier. Clients should branch
+// and localize on codes rather than on messages, which may change. Codes are
+// never renamed or reused once published.
+type Code string
+
+// Generic codes, used when an error carries no more specific one.
+const (
+	CodeBadRequest         Code = "BAD_REQUEST"
+	CodeUnauthorized       Code = "UNAUTHORIZED"
+	CodeForbidden          Code = "FORBIDDEN"
+	CodeNotFound           Code = "NOT_FOUND"
+	CodeMethodNotAllowed   Code = "METHOD_NOT_ALLOWED"
+	CodeConflict           Code = "CONFLICT"
+	CodeInternal           Code = "INTERNAL_ERROR"
+	CodeServiceUnavailable Code = "SERVICE_UNAVAILABLE"
+)
+
+// Domain codes.
+const (
+	CodeValidationFailed       Code = "VALIDATION_FAILED"
+	CodeMalformedBody          Code = "MALFORMED_BODY"
+	CodeInvalidLimit           Code = "INVALID_LIMIT"
+	CodeInvalidUserID          Code = "INVALID_USER_ID"
+	CodeSearchCriteriaRequired Code = "SEARCH_CRITERIA_REQUIRED"
+	CodeUserModifyForbidden    Code = "USER_MODIFY_FORBIDDEN"
+	CodeUserNotFound           Code = "USER_NOT_FOUND"
+	CodeUserEmailTaken         Code = "USER_EMAIL_TAKEN"
+	CodeUserNameTaken          Code = "USER_NAME_TAKEN"
+	CodeAuthInvalidCredentials Code = "AUTH_INVALID_CREDENTIALS"
+	CodeAuthUserInactive       Code = "AUTH_USER_INACTIVE"
+	CodeAuthUserDisabled       Code = "AUTH_USER_DISABLED"
+	CodeAuthWrongPassword      Code = "AUTH_WRONG_PASSWORD"
+	CodeAuthInvalidToken       Code = "AUTH_INVALID_TOKEN"
+	CodeAuthMissingBearer      Code = "AUTH_MISSING_BEARER"
+	CodeAuthInsufficientRole   Code = "AUTH_INSUFFICIENT_ROLE"
+	CodeAuthInvalidAPIKey      Code = "AUTH_INVALID_API_KEY"
+	CodeRefreshTokenInvalid    Code = "REFRESH_TOKEN_INVALID"
+	CodeVerifyTokenInvalid     Code = "VERIFY_TOKEN_INVALID"
+	CodeResetTokenInvalid      Code = "RESET_TOKEN_INVALID"
+	CodeKeyNotFound            Code = "KEY_NOT_FOUND"
+	CodeKeyRotationDisabled    Code = "KEY_ROTATION_DISABLED"
+)
+
+// Field codes, used in the errors array of validation problems.
+const (
+	CodeFieldRequired      Code = "FIELD_REQUIRED"
+	CodeFieldInvalidEmail  Code = "FIELD_INVALID_EMAIL"
+	CodeFieldTooShort      Code = "FIELD_TOO_SHORT"
+	CodeFieldContainsSpace Code = "FIELD_CONTAINS_SPACE"
+)
+
+// statusCode returns the generic code for an HTTP status.
+func statusCode(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	}
+	if status < http.StatusInternalServerError {
+		return CodeBadRequest
+	}
+	return CodeInternal
+}
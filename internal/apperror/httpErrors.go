@@ -1,11 +1,53 @@
 package apperror
 
-import "net/http"
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+// Problem types used in the type member of error responses. RFC 7807 lets a
+// relative URI stand for a problem type.
+const (
+	// TypeBlank means the problem has no semantics beyond its status code.
+	TypeBlank      = "about:blank"
+	TypeValidation = "/problems/validation"
+)
 
 type HTTPError struct {
 	Err        error
 	StatusCode int
 	Message    string
+	// Type is a URI identifying the kind of problem. Empty means TypeBlank.
+	Type string
+	// Title is a short summary of Type. Empty means the localized status
+	// text.
+	Title string
+	// Code identifies the error for clients. When it has a catalog message
+	// that message, localized, replaces the error text as the detail. Empty
+	// means the generic code of StatusCode.
+	Code Code
+	// Errors lists the individual fields that failed validation.
+	Errors []FieldError
+}
+
+// FieldError is a single violation found while validating a request. Field
+// is the name of the offending JSON field. Detail is replaced by the
+// localized message of Code, with Params filled in, when there is one.
+type FieldError struct {
+	Field  string            `json:"field"`
+	Code   Code              `json:"code"`
+	Detail string            `json:"detail"`
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// WithCode sets the error code and returns e.
+func (e *HTTPError) WithCode(code Code) *HTTPError {
+	e.Code = code
+	return e
 }
 
 func (e *HTTPError) Error() string {
@@ -50,6 +92,104 @@ func NotFound(err error) *HTTPError {
 	return NewHTTPError(err, http.StatusNotFound)
 }
 
+func Conflict(err error) *HTTPError {
+	return NewHTTPError(err, http.StatusConflict)
+}
+
 func InternalServerError(err error) *HTTPError {
 	return NewHTTPError(err, http.StatusInternalServerError)
 }
+
+// Validation reports a request whose fields failed validation. All
+// violations should be passed at once so clients can fix them in one go.
+func Validation(err error, fields ...FieldError) *HTTPError {
+	return &HTTPError{
+		Err:        err,
+		StatusCode: http.StatusBadRequest,
+		Type:       TypeValidation,
+		Code:       CodeValidationFailed,
+		Errors:     fields,
+	}
+}
+
+// ContentType is the media type of error responses.
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, extended with a stable
+// error code.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Code     Code         `json:"code"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// Write sends err as a problem details body, using the path of r as the
+// instance and localizing messages for its Accept-Language header. A detail
+// without a catalog message can't be localized, so the whole problem is then
+// written in English. Errors that aren't an *HTTPError are reported as a
+// generic 500, and a 5xx without a catalog message gets the generic message of
+// its status as the detail, so internal details don't leak; the error itself
+// is logged through slog instead. An *OAuthError is written in the OAuth
+// shape.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	var oauthErr *OAuthError
+	if errors.As(err, &oauthErr) {
+		if oauthErr.StatusCode >= http.StatusInternalServerError {
+			logServerError(r, oauthErr.StatusCode, err)
+		}
+		writeOAuth(w, oauthErr)
+		return
+	}
+	locale := Locale(r.Header.Get("Accept-Language"))
+	p := Problem{
+		Type:     TypeBlank,
+		Status:   http.StatusInternalServerError,
+		Code:     CodeInternal,
+		Instance: r.URL.Path,
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		p.Status = httpErr.StatusCode
+		p.Code = statusCode(httpErr.StatusCode)
+		p.Detail = httpErr.Error()
+		if httpErr.Type != "" {
+			p.Type = httpErr.Type
+		}
+		p.Title = httpErr.Title
+		if httpErr.Code != "" {
+			p.Code = httpErr.Code
+		}
+		if msg, ok := Message(locale, httpErr.Code, nil); ok {
+			p.Detail = msg
+		} else if p.Status >= http.StatusInternalServerError {
+			p.Detail, _ = Message(locale, statusCode(p.Status), nil)
+		} else {
+			locale = language.English
+		}
+		for _, f := range httpErr.Errors {
+			if msg, ok := Message(locale, f.Code, f.Params); ok {
+				f.Detail = msg
+			}
+			p.Errors = append(p.Errors, f)
+		}
+	}
+	if p.Title == "" {
+		p.Title, _ = Message(locale, statusCode(p.Status), nil)
+	}
+	if p.Status >= http.StatusInternalServerError {
+		logServerError(r, p.Status, err)
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Language", locale.String())
+	w.Header().Add("Vary", "Accept-Language")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+func logServerError(r *http.Request, status int, err error) {
+	slog.ErrorContext(r.Context(), "Request failed", "method", r.Method, "path", r.URL.Path, "status", status, "error", err)
+}
@@ -1,8 +1,12 @@
 package apperror
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"testing"
 )
 
@@ -90,3 +94,159 @@ func TestHelperConstructors_SetStatusCodesAndPropagateError(t *testing.T) {
 		})
 	}
 }
+
+func TestWrite(t *testing.T) {
+	fields := []FieldError{{Field: "password", Code: CodeFieldTooShort, Params: map[string]string{"min": "8"}}}
+	tests := []struct {
+		name           string
+		err            error
+		acceptLanguage string
+		want           Problem
+		wantLanguage   string
+	}{
+		{
+			name: "HTTPError",
+			err:  NotFound(errSentinel),
+			want: Problem{Type: TypeBlank, Title: "Not found", Status: http.StatusNotFound, Code: CodeNotFound, Detail: errSentinel.Error()},
+		},
+		{
+			name: "wrapped HTTPError",
+			err:  fmt.Errorf("wrapped: %w", Forbidden(errSentinel)),
+			want: Problem{Type: TypeBlank, Title: "Forbidden", Status: http.StatusForbidden, Code: CodeForbidden, Detail: errSentinel.Error()},
+		},
+		{
+			name: "coded HTTPError",
+			err:  Conflict(errSentinel).WithCode(CodeUserEmailTaken),
+			want: Problem{Type: TypeBlank, Title: "Conflict", Status: http.StatusConflict, Code: CodeUserEmailTaken, Detail: "This email address is already registered."},
+		},
+		{
+			name: "validation",
+			err:  Validation(errSentinel, fields...),
+			want: Problem{
+				Type:   TypeValidation,
+				Title:  "Bad request",
+				Status: http.StatusBadRequest,
+				Code:   CodeValidationFailed,
+				Detail: "The request contains invalid fields.",
+				Errors: []FieldError{{Field: "password", Code: CodeFieldTooShort, Detail: "must be at least 8 characters", Params: map[string]string{"min": "8"}}},
+			},
+		},
+		{
+			name:           "validation in German",
+			err:            Validation(errSentinel, fields...),
+			acceptLanguage: "de-CH, en;q=0.5",
+			want: Problem{
+				Type:   TypeValidation,
+				Title:  "Ungültige Anfrage",
+				Status: http.StatusBadRequest,
+				Code:   CodeValidationFailed,
+				Detail: "Die Anfrage enthält ungültige Felder.",
+				Errors: []FieldError{{Field: "password", Code: CodeFieldTooShort, Detail: "muss mindestens 8 Zeichen lang sein", Params: map[string]string{"min": "8"}}},
+			},
+			wantLanguage: "de",
+		},
+		{
+			name:           "uncoded HTTPError in German",
+			err:            NotFound(errSentinel),
+			acceptLanguage: "de",
+			want:           Problem{Type: TypeBlank, Title: "Not found", Status: http.StatusNotFound, Code: CodeNotFound, Detail: errSentinel.Error()},
+			wantLanguage:   "en",
+		},
+		{
+			name: "uncoded 5xx HTTPError",
+			err:  InternalServerError(errors.New("pq: relation \"signing_keys\" does not exist")),
+			want: Problem{Type: TypeBlank, Title: "Internal server error", Status: http.StatusInternalServerError, Code: CodeInternal, Detail: "Internal server error"},
+		},
+		{
+			name:           "uncoded 5xx HTTPError in German",
+			err:            InternalServerError(errSentinel),
+			acceptLanguage: "de",
+			want:           Problem{Type: TypeBlank, Title: "Interner Serverfehler", Status: http.StatusInternalServerError, Code: CodeInternal, Detail: "Interner Serverfehler"},
+			wantLanguage:   "de",
+		},
+		{
+			name:           "unsupported language",
+			err:            errSentinel,
+			acceptLanguage: "fr",
+			want:           Problem{Type: TypeBlank, Title: "Internal server error", Status: http.StatusInternalServerError, Code: CodeInternal},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/user/42", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			Write(w, req, tt.err)
+			if w.Code != tt.want.Status {
+				t.Fatalf("expected status %d, got %d", tt.want.Status, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != ContentType {
+				t.Fatalf("expected problem+json content type, got %q", ct)
+			}
+			if cl := w.Header().Get("Content-Language"); tt.wantLanguage != "" && cl != tt.wantLanguage {
+				t.Errorf("expected Content-Language %q, got %q", tt.wantLanguage, cl)
+			}
+			var body Problem
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Instance = "/user/42"
+			if !reflect.DeepEqual(body, tt.want) {
+				t.Fatalf("expected body %+v, got %+v", tt.want, body)
+			}
+		})
+	}
+}
+
+func TestWrite_OAuthError(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/token/revoke", nil)
+	req.Header.Set("Accept-Language", "de")
+	Write(w, req, fmt.Errorf("wrapped: %w", InvalidRequest(errors.New("token must be provided"))))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON content type, got %q", ct)
+	}
+	if cl := w.Header().Get("Content-Language"); cl != "en" {
+		t.Errorf("expected Content-Language en, got %q", cl)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"error": OAuthInvalidRequest, "error_description": "token must be provided"}
+	if !reflect.DeepEqual(body, want) {
+		t.Fatalf("expected body %v, got %v", want, body)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		acceptLanguage string
+		want           string
+	}{
+		{acceptLanguage: "", want: "en"},
+		{acceptLanguage: "de", want: "de"},
+		{acceptLanguage: "fr-FR, de;q=0.8", want: "de"},
+		{acceptLanguage: "en-GB, de;q=0.8", want: "en"},
+		{acceptLanguage: "not a language", want: "en"},
+	}
+	for _, tt := range tests {
+		if got := Locale(tt.acceptLanguage).String(); got != tt.want {
+			t.Errorf("Locale(%q) = %s, want %s", tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
+
+func TestMessages_Complete(t *testing.T) {
+	for _, locale := range locales {
+		for code := range messages[locales[0]] {
+			if _, ok := messages[locale][code]; !ok {
+				t.Errorf("locale %s has no message for %s", locale, code)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package apperror
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// locales are the bundled message catalogs, best first. English is the
+// fallback and has a message for every code.
+var locales = []language.Tag{language.English, language.German}
+
+var matcher = language.NewMatcher(locales)
+
+// messages holds the text for each code. The messages of the generic codes
+// double as problem titles. {name} placeholders are filled from the params
+// of a FieldError.
+var messages = map[language.Tag]map[Code]string{
+	language.English: {
+		CodeBadRequest:         "Bad request",
+		CodeUnauthorized:       "Unauthorized",
+		CodeForbidden:          "Forbidden",
+		CodeNotFound:           "Not found",
+		CodeMethodNotAllowed:   "Method not allowed",
+		CodeConflict:           "Conflict",
+		CodeInternal:           "Internal server error",
+		CodeServiceUnavailable: "Service unavailable",
+
+		CodeValidationFailed:       "The request contains invalid fields.",
+		CodeMalformedBody:          "The request body is not valid JSON or has fields of the wrong type.",
+		CodeInvalidLimit:           "The limit must be a positive whole number.",
+		CodeInvalidUserID:          "The user ID is not a valid UUID.",
+		CodeSearchCriteriaRequired: "Provide a name or an email address to search for.",
+		CodeUserModifyForbidden:    "You may only change your own account.",
+		CodeUserNotFound:           "The user does not exist.",
+		CodeUserEmailTaken:         "This email address is already registered.",
+		CodeUserNameTaken:          "This name is already taken.",
+		CodeAuthInvalidCredentials: "The name, email or password is incorrect.",
+		CodeAuthUserInactive:       "The account has not been activated yet.",
+		CodeAuthUserDisabled:       "The account has been disabled.",
+		CodeAuthWrongPassword:      "The current password is incorrect.",
+		CodeAuthInvalidToken:       "The access token is missing, invalid or expired.",
+		CodeAuthMissingBearer:      "A bearer token must be provided.",
+		CodeAuthInsufficientRole:   "You are not allowed to do this.",
+		CodeAuthInvalidAPIKey:      "The API key is missing or invalid.",
+		CodeRefreshTokenInvalid:    "The refresh token is invalid, expired or has already been used.",
+		CodeVerifyTokenInvalid:     "The verification link is invalid or has expired.",
+		CodeResetTokenInvalid:      "The password reset link is invalid or has expired.",
+		CodeKeyNotFound:            "The signing key does not exist.",
+		CodeKeyRotationDisabled:    "Key rotation is not enabled.",
+
+		CodeFieldRequired:      "must not be empty",
+		CodeFieldInvalidEmail:  "must be a valid email address",
+		CodeFieldTooShort:      "must be at least {min} characters",
+		CodeFieldContainsSpace: "must not contain spaces",
+	},
+	language.German: {
+		CodeBadRequest:         "Ungültige Anfrage",
+		CodeUnauthorized:       "Nicht angemeldet",
+		CodeForbidden:          "Zugriff verweigert",
+		CodeNotFound:           "Nicht gefunden",
+		CodeMethodNotAllowed:   "Methode nicht erlaubt",
+		CodeConflict:           "Konflikt",
+		CodeInternal:           "Interner Serverfehler",
+		CodeServiceUnavailable: "Dienst nicht verfügbar",
+
+		CodeValidationFailed:       "Die Anfrage enthält ungültige Felder.",
+		CodeMalformedBody:          "Der Anfragetext ist kein gültiges JSON oder enthält Felder mit falschem Typ.",
+		CodeInvalidLimit:           "Das Limit muss eine positive ganze Zahl sein.",
+		CodeInvalidUserID:          "Die Benutzer-ID ist keine gültige UUID.",
+		CodeSearchCriteriaRequired: "Geben Sie einen Namen oder eine E-Mail-Adresse für die Suche an.",
+		CodeUserModifyForbidden:    "Sie dürfen nur Ihr eigenes Konto ändern.",
+		CodeUserNotFound:           "Der Benutzer existiert nicht.",
+		CodeUserEmailTaken:         "Diese E-Mail-Adresse ist bereits registriert.",
+		CodeUserNameTaken:          "Dieser Name ist bereits vergeben.",
+		CodeAuthInvalidCredentials: "Name, E-Mail-Adresse oder Passwort ist falsch.",
+		CodeAuthUserInactive:       "Das Konto wurde noch nicht aktiviert.",
+		CodeAuthUserDisabled:       "Das Konto wurde gesperrt.",
+		CodeAuthWrongPassword:      "Das aktuelle Passwort ist falsch.",
+		CodeAuthInvalidToken:       "Das Zugriffstoken fehlt, ist ungültig oder abgelaufen.",
+		CodeAuthMissingBearer:      "Ein Bearer-Token muss angegeben werden.",
+		CodeAuthInsufficientRole:   "Dazu fehlt Ihnen die Berechtigung.",
+		CodeAuthInvalidAPIKey:      "Der API-Schlüssel fehlt oder ist ungültig.",
+		CodeRefreshTokenInvalid:    "Das Aktualisierungstoken ist ungültig, abgelaufen oder wurde bereits verwendet.",
+		CodeVerifyTokenInvalid:     "Der Bestätigungslink ist ungültig oder abgelaufen.",
+		CodeResetTokenInvalid:      "Der Link zum Zurücksetzen des Passworts ist ungültig oder abgelaufen.",
+		CodeKeyNotFound:            "Der Signaturschlüssel existiert nicht.",
+		CodeKeyRotationDisabled:    "Die Schlüsselrotation ist nicht aktiviert.",
+
+		CodeFieldRequired:      "darf nicht leer sein",
+		CodeFieldInvalidEmail:  "muss eine gültige E-Mail-Adresse sein",
+		CodeFieldTooShort:      "muss mindestens {min} Zeichen lang sein",
+		CodeFieldContainsSpace: "darf keine Leerzeichen enthalten",
+	},
+}
+
+// Locale returns the bundled locale that best matches an Accept-Language
+// header, or English when nothing matches.
+func Locale(acceptLanguage string) language.Tag {
+	tags, _, _ := language.ParseAcceptLanguage(acceptLanguage)
+	_, i, _ := matcher.Match(tags...)
+	return locales[i]
+}
+
+// Message returns the text for code in locale, falling back to English. It
+// reports false when the code has no message.
+func Message(locale language.Tag, code Code, params map[string]string) (string, bool) {
+	msg, ok := messages[locale][code]
+	if !ok {
+		msg, ok = messages[language.English][code]
+	}
+	if !ok {
+		return "", false
+	}
+	for name, value := range params {
+		msg = strings.ReplaceAll(msg, "{"+name+"}", value)
+	}
+	return msg, true
+}
@@ -0,0 +1,61 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// OAuth error codes from RFC 6749 section 5.2, plus the codes commonly used
+// for server failures on the token endpoints.
+const (
+	OAuthInvalidRequest         = "invalid_request"
+	OAuthInvalidClient          = "invalid_client"
+	OAuthServerError            = "server_error"
+	OAuthTemporarilyUnavailable = "temporarily_unavailable"
+)
+
+// OAuthError is written as an OAuth error response instead of a problem
+// details body. The token revocation and introspection endpoints use it, as
+// their clients expect the shape RFC 6749 defines.
+type OAuthError struct {
+	Err        error
+	StatusCode int
+	// Code is the value of the error member, such as OAuthInvalidRequest.
+	Code string
+	// Description is sent as error_description. It is English only and
+	// should not reveal internal details.
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Err.Error()
+}
+
+func (e *OAuthError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidRequest reports a malformed request, describing it with the text of
+// err.
+func InvalidRequest(err error) *OAuthError {
+	return &OAuthError{Err: err, StatusCode: http.StatusBadRequest, Code: OAuthInvalidRequest, Description: err.Error()}
+}
+
+// InvalidClient reports failed client authentication. Callers should set
+// WWW-Authenticate.
+func InvalidClient(err error) *OAuthError {
+	return &OAuthError{Err: err, StatusCode: http.StatusUnauthorized, Code: OAuthInvalidClient, Description: "client authentication failed"}
+}
+
+type oauthBody struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func writeOAuth(w http.ResponseWriter, e *OAuthError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Language", "en")
+	w.WriteHeader(e.StatusCode)
+	json.NewEncoder(w).Encode(oauthBody{Error: e.Code, Description: e.Description})
+}
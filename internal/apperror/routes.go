@@ -0,0 +1,45 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// routeMethods are the methods MethodNotAllowedHandler checks when listing
+// what a path accepts.
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// NotFoundHandler answers requests that match no route with a problem
+// details body instead of the router's plain text 404.
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	Write(w, r, NotFound(errors.New("no route matches "+r.URL.Path)).WithCode(CodeNotFound))
+}
+
+// MethodNotAllowedHandler returns a handler answering requests whose path
+// routes knows, but not for their method, with a problem details body. The
+// Allow header lists the methods routes accepts for the path, as RFC 9110
+// requires of a 405.
+func MethodNotAllowedHandler(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.RawPath
+		if path == "" {
+			path = r.URL.Path
+		}
+		for _, method := range routeMethods {
+			if routes.Match(chi.NewRouteContext(), method, path) {
+				w.Header().Add("Allow", method)
+			}
+		}
+		Write(w, r, NewHTTPError(errors.New(r.Method+" is not allowed on "+r.URL.Path), http.StatusMethodNotAllowed).WithCode(CodeMethodNotAllowed))
+	}
+}
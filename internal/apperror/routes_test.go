@@ -0,0 +1,90 @@
+package apperror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func newTestRouter() *chi.Mux {
+	r := chi.NewRouter()
+	r.NotFound(NotFoundHandler)
+	r.MethodNotAllowed(MethodNotAllowedHandler(r))
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r.Get("/user/{id}", ok)
+	r.Delete("/user/{id}", ok)
+	r.Route("/admin", func(r chi.Router) {
+		r.Post("/keys/rotate", ok)
+	})
+	return r
+}
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	if got := w.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("Content-Type = %q, want %q", got, ContentType)
+	}
+	var p Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestNotFoundHandler(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/nowhere", nil)
+	req.Header.Set("Accept-Language", "de")
+	w := httptest.NewRecorder()
+
+	newTestRouter().ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	p := decodeProblem(t, w)
+	want := Problem{Type: TypeBlank, Title: "Nicht gefunden", Status: http.StatusNotFound, Code: CodeNotFound, Detail: "Nicht gefunden", Instance: "/nowhere"}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("problem = %+v, want %+v", p, want)
+	}
+}
+
+func TestMethodNotAllowedHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantAllow []string
+	}{
+		{name: "route", method: http.MethodPost, path: "/user/42", wantAllow: []string{http.MethodGet, http.MethodDelete}},
+		{name: "subrouter", method: http.MethodGet, path: "/admin/keys/rotate", wantAllow: []string{http.MethodPost}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			newTestRouter().ServeHTTP(w, req)
+
+			if w.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+			}
+			allow := w.Header().Values("Allow")
+			if len(allow) != len(tt.wantAllow) {
+				t.Fatalf("Allow = %v, want %v", allow, tt.wantAllow)
+			}
+			for i := range allow {
+				if allow[i] != tt.wantAllow[i] {
+					t.Errorf("Allow = %v, want %v", allow, tt.wantAllow)
+				}
+			}
+			p := decodeProblem(t, w)
+			if p.Status != http.StatusMethodNotAllowed || p.Code != CodeMethodNotAllowed || p.Title != "Method not allowed" {
+				t.Errorf("problem = %+v, want a 405 %s problem", p, CodeMethodNotAllowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package audit records security-relevant account events.
+package audit
+
+import (
+	"log/slog"
+	"time"
+)
+
+type Action string
+
+const (
+	ActionPasswordChanged Action = "password.changed"
+	ActionPasswordReset   Action = "password.reset"
+	ActionUserUpdated     Action = "user.updated"
+	ActionUserDeleted     Action = "user.deleted"
+	ActionUserRestored    Action = "user.restored"
+	ActionUserActivated   Action = "user.activated"
+	ActionUserDeactivated Action = "user.deactivated"
+	ActionUserDisabled    Action = "user.disabled"
+	ActionUserEnabled     Action = "user.enabled"
+)
+
+// Event is a single audited action. ActorID is who performed it; for
+// self-service actions it equals UserID and for operator commands it is the
+// nil UUID.
+type Event struct {
+	Action  Action
+	UserID  string
+	ActorID string
+	Time    time.Time
+}
+
+type Recorder interface {
+	Record(Event) error
+}
+
+// LogRecorder writes events to a structured logger.
+type LogRecorder struct {
+	logger *slog.Logger
+}
+
+func NewLogRecorder(logger *slog.Logger) *LogRecorder {
+	return &LogRecorder{
+		logger: logger,
+	}
+}
+
+func (r *LogRecorder) Record(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	r.logger.Info("audit",
+		"action", string(e.Action),
+		"user_id", e.UserID,
+		"actor_id", e.ActorID,
+		"occurred_at", e.Time.UTC(),
+	)
+	return nil
+}
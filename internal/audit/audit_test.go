@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLogRecorder_Record(t *testing.T) {
+	var buf bytes.Buffer
+	r := NewLogRecorder(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	err := r.Record(Event{
+		Action:  ActionPasswordChanged,
+		UserID:  "user",
+		ActorID: "actor",
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"msg":         "audit",
+		"action":      "password.changed",
+		"user_id":     "user",
+		"actor_id":    "actor",
+		"occurred_at": "2024-01-02T03:04:05Z",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Record() logged %s = %v, want %v", k, got[k], v)
+		}
+	}
+}
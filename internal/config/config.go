@@ -0,0 +1,382 @@
+// Package config loads the service configuration. Values come from, in
+// increasing order of precedence: built-in defaults, an optional YAML or JSON
+// file, an optional .env file and the process environment. Every variable
+// can instead be read from a file by setting NAME_FILE, which is how
+// container secrets are usually mounted.
+package config
+
+import (
+	"awesomeProject/internal/mail"
+	"awesomeProject/internal/signing"
+	"awesomeProject/internal/telemetry"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	HTTP     HTTP             `yaml:"http"`
+	Database Database         `yaml:"database"`
+	Signing  signing.Config   `yaml:"signing"`
+	Mail     mail.Config      `yaml:"mail"`
+	Accounts Accounts         `yaml:"accounts"`
+	FGA      FGA              `yaml:"fga"`
+	Health   Health           `yaml:"health"`
+	Tracing  telemetry.Config `yaml:"tracing"`
+	// AdminAPIKey guards the operator routes; they are disabled when empty.
+	AdminAPIKey string `yaml:"admin_api_key"`
+	// IntrospectionClients maps client IDs to secrets, written as
+	// "id:secret,id:secret".
+	IntrospectionClients string `yaml:"introspection_clients"`
+}
+
+type HTTP struct {
+	Addr              string        `yaml:"addr"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownDelay is how long the server keeps serving after readiness
+	// starts failing, so load balancers stop routing to it first.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+	// ShutdownTimeout bounds how long in-flight requests get to finish.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+}
+
+type Database struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
+	Name     string `yaml:"name"`
+	SSLMode  string `yaml:"sslmode"`
+}
+
+// ConnString returns the database URL for pgx.
+func (d Database) ConnString() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     fmt.Sprintf("%s:%d", d.Host, d.Port),
+		Path:     d.Name,
+		RawQuery: "sslmode=" + url.QueryEscape(d.SSLMode),
+	}
+	return u.String()
+}
+
+type Accounts struct {
+	VerifyURL         string `yaml:"verify_url"`
+	ResetURL          string `yaml:"reset_url"`
+	RequireActivation bool   `yaml:"require_activation"`
+	RolesURL          string `yaml:"roles_url"`
+}
+
+// Health configures the readiness checks.
+type Health struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+	// CacheTTL is how long a check result is reused between probes.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+type FGA struct {
+	APIURL  string `yaml:"api_url"`
+	StoreID string `yaml:"store_id"`
+}
+
+func Default() *Config {
+	return &Config{
+		HTTP: HTTP{
+			Addr:              ":4000",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: Database{
+			Port:    5432,
+			SSLMode: "disable",
+		},
+		Signing:  signing.DefaultConfig(),
+		Accounts: Accounts{RolesURL: "http://localhost:4001"},
+		Tracing:  telemetry.DefaultConfig(),
+		Health: Health{
+			CheckTimeout: 2 * time.Second,
+			CacheTTL:     time.Second,
+		},
+	}
+}
+
+// ValidationError lists every problem found in a configuration so they can be
+// fixed in one go.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load reads the configuration. file is the optional YAML or JSON file and
+// falls back to CONFIG_FILE; a missing .env is not an error.
+func Load(file string) (*Config, error) {
+	return load(file, ".env")
+}
+
+func load(file, envFile string) (*Config, error) {
+	err := godotenv.Load(envFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load %s: %w", envFile, err)
+	}
+
+	c := Default()
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+	if file != "" {
+		if err := c.readFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	var problems []string
+	for _, b := range c.bindings() {
+		v, ok, err := lookupEnv(b.name)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := b.set(v); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", b.name, err))
+		}
+	}
+	problems = append(problems, c.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return c, nil
+}
+
+// readFile decodes a YAML file; JSON is read the same way since it is valid
+// YAML. Unknown keys are rejected so typos don't go unnoticed.
+func (c *Config) readFile(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", file, err)
+	}
+	return nil
+}
+
+// lookupEnv reads name, or the file named by name_FILE.
+func lookupEnv(name string) (string, bool, error) {
+	v, ok := os.LookupEnv(name)
+	path, fromFile := os.LookupEnv(name + "_FILE")
+	if !fromFile {
+		return v, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("only one of %s and %s_FILE may be set", name, name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+type binding struct {
+	name string
+	set  func(string) error
+}
+
+func (c *Config) bindings() []binding {
+	return []binding{
+		stringVar("HTTP_ADDR", &c.HTTP.Addr),
+		durationVar("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout),
+		durationVar("HTTP_READ_HEADER_TIMEOUT", &c.HTTP.ReadHeaderTimeout),
+		durationVar("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout),
+		durationVar("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout),
+		durationVar("HTTP_SHUTDOWN_DELAY", &c.HTTP.ShutdownDelay),
+		durationVar("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout),
+
+		stringVar("DB_HOST", &c.Database.Host),
+		intVar("DB_PORT", &c.Database.Port),
+		stringVar("DB_USER", &c.Database.User),
+		stringVar("DB_PASSWORD", &c.Database.Password),
+		stringVar("DB_NAME", &c.Database.Name),
+		stringVar("DB_SSLMODE", &c.Database.SSLMode),
+
+		stringVar("SIGN_ALG", &c.Signing.Alg),
+		stringVar("SIGN_KEY", &c.Signing.Key),
+		stringVar("SIGN_KEY_FILE", &c.Signing.KeyFile),
+		stringVar("SIGN_KEY_ID", &c.Signing.KeyID),
+		durationVar("SIGN_ROTATE_EVERY", &c.Signing.RotateEvery),
+		durationVar("SIGN_RETIRE_AFTER", &c.Signing.RetireAfter),
+
+		stringVar("MAIL_SENDER", &c.Mail.Sender),
+		stringVar("MAIL_DIR", &c.Mail.Dir),
+		stringVar("SMTP_ADDR", &c.Mail.SMTPAddr),
+		stringVar("SMTP_USERNAME", &c.Mail.SMTPUsername),
+		stringVar("SMTP_PASSWORD", &c.Mail.SMTPPassword),
+		stringVar("MAIL_FROM", &c.Mail.From),
+
+		stringVar("VERIFY_URL", &c.Accounts.VerifyURL),
+		stringVar("RESET_URL", &c.Accounts.ResetURL),
+		boolVar("REQUIRE_ACTIVATION", &c.Accounts.RequireActivation),
+		stringVar("ROLES_URL", &c.Accounts.RolesURL),
+
+		stringVar("FGA_API_URL", &c.FGA.APIURL),
+		stringVar("FGA_STORE_ID", &c.FGA.StoreID),
+
+		durationVar("HEALTH_CHECK_TIMEOUT", &c.Health.CheckTimeout),
+		durationVar("HEALTH_CACHE_TTL", &c.Health.CacheTTL),
+
+		stringVar("OTEL_TRACES_EXPORTER", &c.Tracing.Exporter),
+		stringVar("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.Endpoint),
+		stringVar("OTEL_SERVICE_NAME", &c.Tracing.ServiceName),
+		floatVar("OTEL_TRACES_SAMPLER_ARG", &c.Tracing.SampleRatio),
+
+		stringVar("ADMIN_API_KEY", &c.AdminAPIKey),
+		stringVar("INTROSPECTION_CLIENTS", &c.IntrospectionClients),
+	}
+}
+
+func stringVar(name string, p *string) binding {
+	return binding{name: name, set: func(v string) error {
+		*p = v
+		return nil
+	}}
+}
+
+func intVar(name string, p *int) binding {
+	return binding{name: name, set: func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		*p = n
+		return nil
+	}}
+}
+
+func floatVar(name string, p *float64) binding {
+	return binding{name: name, set: func(v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		*p = f
+		return nil
+	}}
+}
+
+func boolVar(name string, p *bool) binding {
+	return binding{name: name, set: func(v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*p = b
+		return nil
+	}}
+}
+
+func durationVar(name string, p *time.Duration) binding {
+	return binding{name: name, set: func(v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*p = d
+		return nil
+	}}
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+	if c.HTTP.Addr == "" {
+		problems = append(problems, "HTTP_ADDR is required")
+	}
+	for name, d := range map[string]time.Duration{
+		"HTTP_READ_TIMEOUT":        c.HTTP.ReadTimeout,
+		"HTTP_READ_HEADER_TIMEOUT": c.HTTP.ReadHeaderTimeout,
+		"HTTP_WRITE_TIMEOUT":       c.HTTP.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":        c.HTTP.IdleTimeout,
+		"HTTP_SHUTDOWN_DELAY":      c.HTTP.ShutdownDelay,
+		"HEALTH_CACHE_TTL":         c.Health.CacheTTL,
+	} {
+		if d < 0 {
+			problems = append(problems, name+" must not be negative")
+		}
+	}
+	if c.HTTP.ShutdownTimeout <= 0 {
+		problems = append(problems, "HTTP_SHUTDOWN_TIMEOUT must be positive")
+	}
+	if c.Health.CheckTimeout <= 0 {
+		problems = append(problems, "HEALTH_CHECK_TIMEOUT must be positive")
+	}
+	for name, v := range map[string]string{
+		"DB_HOST": c.Database.Host,
+		"DB_USER": c.Database.User,
+		"DB_NAME": c.Database.Name,
+	} {
+		if v == "" {
+			problems = append(problems, name+" is required")
+		}
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		problems = append(problems, "DB_PORT must be between 1 and 65535")
+	}
+	for _, err := range c.Signing.Validate() {
+		problems = append(problems, err.Error())
+	}
+	if err := c.Mail.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if _, err := c.Clients(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	// Map iteration order is random; keep the report stable.
+	slices.Sort(problems)
+	return problems
+}
+
+// Clients parses IntrospectionClients.
+func (c *Config) Clients() (map[string]string, error) {
+	clients := make(map[string]string)
+	for _, pair := range strings.Split(c.IntrospectionClients, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		// The entry holds a secret, so it is left out of the error.
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("INTROSPECTION_CLIENTS entries must look like id:secret")
+		}
+		clients[id] = secret
+	}
+	return clients, nil
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setRequired sets the variables without defaults.
+func setRequired(t *testing.T) {
+	t.Helper()
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "auth")
+	t.Setenv("DB_NAME", "auth")
+	t.Setenv("SIGN_KEY", "secret")
+	t.Setenv("MAIL_SENDER", "log")
+}
+
+// unset clears name for the test and restores it afterwards.
+func unset(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadTest(t *testing.T, file string) (*Config, error) {
+	t.Helper()
+	return load(file, filepath.Join(t.TempDir(), ".env"))
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	unset(t, "CONFIG_FILE", "HTTP_ADDR", "DB_PORT", "DB_SSLMODE", "ROLES_URL")
+	setRequired(t)
+	c, err := loadTest(t, "")
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if c.HTTP.Addr != ":4000" || c.Database.Port != 5432 || c.Database.SSLMode != "disable" {
+		t.Errorf("load() = %+v, want defaults", c)
+	}
+	if c.Signing.Key != "secret" {
+		t.Errorf("Signing.Key = %q, want value from SIGN_KEY", c.Signing.Key)
+	}
+}
+
+func TestLoad_Env(t *testing.T) {
+	setRequired(t)
+	t.Setenv("HTTP_ADDR", ":8080")
+	t.Setenv("DB_PORT", "6543")
+	t.Setenv("REQUIRE_ACTIVATION", "true")
+	t.Setenv("SIGN_RETIRE_AFTER", "2h")
+	t.Setenv("HTTP_SHUTDOWN_TIMEOUT", "45s")
+	c, err := loadTest(t, "")
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if c.HTTP.Addr != ":8080" || c.Database.Port != 6543 || !c.Accounts.RequireActivation || c.Signing.RetireAfter != 2*time.Hour || c.HTTP.ShutdownTimeout != 45*time.Second {
+		t.Errorf("load() = %+v, want values from the environment", c)
+	}
+}
+
+func TestLoad_SecretFile(t *testing.T) {
+	setRequired(t)
+	unset(t, "DB_PASSWORD")
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "db_password", "hunter2\n"))
+	c, err := loadTest(t, "")
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if c.Database.Password != "hunter2" {
+		t.Errorf("Database.Password = %q, want the file contents", c.Database.Password)
+	}
+
+	t.Setenv("DB_PASSWORD", "other")
+	if _, err := loadTest(t, ""); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD_FILE") {
+		t.Errorf("load() error = %v, want a conflict between DB_PASSWORD and DB_PASSWORD_FILE", err)
+	}
+}
+
+func TestLoad_File(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `
+http:
+  addr: ":9000"
+  write_timeout: 1m
+database:
+  port: 6000
+accounts:
+  verify_url: http://localhost/verify
+`,
+		},
+		{
+			name:    "json",
+			file:    "config.json",
+			content: `{"http": {"addr": ":9000"}, "database": {"port": 6000}, "accounts": {"verify_url": "http://localhost/verify"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unset(t, "HTTP_ADDR", "VERIFY_URL")
+			setRequired(t)
+			t.Setenv("DB_PORT", "7000")
+			c, err := loadTest(t, writeFile(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("load() error = %v", err)
+			}
+			if c.HTTP.Addr != ":9000" || c.Accounts.VerifyURL != "http://localhost/verify" {
+				t.Errorf("load() = %+v, want values from the file", c)
+			}
+			if tt.name == "yaml" && c.HTTP.WriteTimeout != time.Minute {
+				t.Errorf("HTTP.WriteTimeout = %v, want the duration from the file", c.HTTP.WriteTimeout)
+			}
+			if c.Database.Port != 7000 {
+				t.Errorf("Database.Port = %d, want the environment to override the file", c.Database.Port)
+			}
+		})
+	}
+}
+
+func TestLoad_UnknownFileKey(t *testing.T) {
+	setRequired(t)
+	if _, err := loadTest(t, writeFile(t, "config.yaml", "htpp:\n  addr: \":9000\"\n")); err == nil {
+		t.Errorf("load() should reject unknown keys")
+	}
+}
+
+func TestLoad_DotEnv(t *testing.T) {
+	unset(t, "DB_HOST", "DB_USER", "DB_NAME", "SIGN_KEY", "SIGN_KEY_FILE", "MAIL_SENDER")
+	t.Setenv("DB_NAME", "from-env")
+	env := writeFile(t, ".env", "DB_HOST=db\nDB_USER=auth\nDB_NAME=from-dotenv\nSIGN_KEY=secret\nMAIL_SENDER=log\n")
+	c, err := load("", env)
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if c.Database.Host != "db" || c.Database.Name != "from-env" {
+		t.Errorf("load() = %+v, want .env values below the environment", c.Database)
+	}
+}
+
+func TestLoad_Aggregated(t *testing.T) {
+	unset(t, "DB_HOST", "DB_USER", "DB_NAME", "SIGN_KEY", "SIGN_KEY_FILE", "SIGN_ALG", "MAIL_SENDER")
+	t.Setenv("DB_PORT", "port")
+	t.Setenv("INTROSPECTION_CLIENTS", "gateway")
+	t.Setenv("HTTP_SHUTDOWN_TIMEOUT", "0s")
+	_, err := loadTest(t, "")
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("load() error = %v, want a ValidationError", err)
+	}
+	for _, want := range []string{"DB_HOST", "DB_USER", "DB_NAME", "DB_PORT", "SIGN_KEY", "MAIL_SENDER", "INTROSPECTION_CLIENTS", "HTTP_SHUTDOWN_TIMEOUT"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error does not mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestConfig_Clients(t *testing.T) {
+	c := &Config{IntrospectionClients: "gateway:s1, billing:s2,"}
+	clients, err := c.Clients()
+	if err != nil {
+		t.Fatalf("Clients() error = %v", err)
+	}
+	if len(clients) != 2 || clients["gateway"] != "s1" || clients["billing"] != "s2" {
+		t.Errorf("Clients() = %v", clients)
+	}
+	c.IntrospectionClients = "gateway:"
+	if _, err := c.Clients(); err == nil {
+		t.Errorf("Clients() should reject an entry without a secret")
+	}
+}
+
+func TestDatabase_ConnString(t *testing.T) {
+	d := Database{Host: "db", Port: 5432, User: "auth", Password: "p@ss/word", Name: "auth", SSLMode: "disable"}
+	want := "postgres://auth:p%40ss%2Fword@db:5432/auth?sslmode=disable"
+	if got := d.ConnString(); got != want {
+		t.Errorf("ConnString() = %q, want %q", got, want)
+	}
+}
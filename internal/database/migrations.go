@@ -2,32 +2,314 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
 	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// RunMigrations executes all database migrations on startup
+// migrationFiles holds the schema as NNNN_name.up.sql / NNNN_name.down.sql
+// pairs. Applied migrations must never be edited; add a new one instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the pg_advisory_lock key held while migrating so that
+// replicas starting together don't apply the same migration twice.
+const migrationLockID int64 = 0x617574685f6d6967 // "auth_mig"
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Checksum identifies the up and down scripts so edits to either after the
+// migration was applied are detected.
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	h.Write([]byte(m.Up))
+	h.Write([]byte{0})
+	h.Write([]byte(m.Down))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// AppliedMigration is a row of the schema_migrations table.
+type AppliedMigration struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrations returns the embedded migrations in version order.
+func Migrations() ([]Migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(sub)
+}
+
+// LoadMigrations reads up/down pairs from the root of fsys. Every version
+// needs both scripts and versions must be unique.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		match := migrationName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		if version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", e.Name())
+		}
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", e.Name(), err)
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d used by both %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down script", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return a.Version - b.Version
+	})
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations, recording them in the
+// schema_migrations table.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(pool *pgxpool.Pool, migrations []Migration) *Migrator {
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+	}
+}
+
+// RunMigrations brings the database up to the latest embedded migration.
 func RunMigrations(ctx context.Context, pool *pgxpool.Pool) error {
-	// Create users table
-	createUsersTable := `
-	CREATE TABLE IF NOT EXISTS users (
-		id UUID PRIMARY KEY,
-		name VARCHAR(255) NOT NULL UNIQUE,
-		email VARCHAR(255) NOT NULL UNIQUE,
-		password_hash BYTEA NOT NULL,
-		joined TIMESTAMP NOT NULL DEFAULT NOW(),
-		activated BOOLEAN NOT NULL DEFAULT FALSE
-	);
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	_, err = NewMigrator(pool, migrations).Up(ctx)
+	return err
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the ones it applied. It refuses to run if an applied migration was
+// edited.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mig.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx,
+					`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+					mig.Version, mig.Name, mig.Checksum(),
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the latest steps applied migrations, newest first, and
+// returns the ones it rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+		versions := make([]int, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		slices.Sort(versions)
+		slices.Reverse(versions)
+		for _, v := range versions[:min(steps, len(versions))] {
+			mig, ok := m.find(v)
+			if !ok {
+				return fmt.Errorf("no down script for applied migration %d", v)
+			}
+			err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mig.Down); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to roll back migration %s: %w", mig, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Applied lists the migrations recorded in schema_migrations in version
+// order.
+func (m *Migrator) Applied(ctx context.Context) ([]AppliedMigration, error) {
+	var list []AppliedMigration
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, a := range applied {
+			list = append(list, a)
+		}
+		return nil
+	})
+	slices.SortFunc(list, func(a, b AppliedMigration) int {
+		return a.Version - b.Version
+	})
+	return list, err
+}
 
-	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
-	CREATE INDEX IF NOT EXISTS idx_users_name ON users(name);
+// verify fails if a known migration was applied with different scripts.
+// Versions unknown to this binary are left alone so an older replica can
+// still start against a newer schema.
+func (m *Migrator) verify(applied map[int]AppliedMigration) error {
+	for _, mig := range m.migrations {
+		a, ok := applied[mig.Version]
+		if ok && a.Checksum != mig.Checksum() {
+			return fmt.Errorf("migration %s was edited after it was applied (checksum %s, applied %s)", mig, mig.Checksum(), a.Checksum)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+// withLock runs fn on a single connection holding the migration advisory
+// lock, creating the schema_migrations table first.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	_, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	createSchemaMigrationsTable := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum VARCHAR(64) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
 	`
 
-	_, err := pool.Exec(ctx, createUsersTable)
+	_, err = conn.Exec(ctx, createSchemaMigrationsTable)
 	if err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
 	}
 
-	return nil
+	return fn(conn)
+}
+
+func appliedMigrations(ctx context.Context, conn *pgxpool.Conn) (map[int]AppliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]AppliedMigration)
+	for rows.Next() {
+		var a AppliedMigration
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations: %w", err)
+		}
+		applied[a.Version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return applied, nil
 }
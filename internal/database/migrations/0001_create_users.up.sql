@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS users (
+	id UUID PRIMARY KEY,
+	name VARCHAR(255) NOT NULL UNIQUE,
+	email VARCHAR(255) NOT NULL UNIQUE,
+	password_hash BYTEA NOT NULL,
+	joined TIMESTAMP NOT NULL DEFAULT NOW(),
+	activated BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
+CREATE INDEX IF NOT EXISTS idx_users_name ON users(name);
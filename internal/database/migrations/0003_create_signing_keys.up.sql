@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS signing_keys (
+	id VARCHAR(255) PRIMARY KEY,
+	algorithm VARCHAR(16) NOT NULL,
+	private_key BYTEA NOT NULL,
+	state VARCHAR(16) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	activated_at TIMESTAMPTZ,
+	retiring_at TIMESTAMPTZ,
+	retired_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_signing_keys_active_pending ON signing_keys(state) WHERE state IN ('active', 'pending');
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+	jti VARCHAR(255) PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	revoked_before TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS action_tokens (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	purpose VARCHAR(32) NOT NULL,
+	token_hash BYTEA NOT NULL UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_action_tokens_user_id ON action_tokens(user_id, purpose);
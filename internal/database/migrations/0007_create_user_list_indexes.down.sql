@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_email_prefix;
+DROP INDEX IF EXISTS idx_users_name_prefix;
+DROP INDEX IF EXISTS idx_users_joined_id;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_users_joined_id ON users(joined, id);
+CREATE INDEX IF NOT EXISTS idx_users_name_prefix ON users(name text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users(email text_pattern_ops);
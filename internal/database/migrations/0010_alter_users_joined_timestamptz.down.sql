@@ -0,0 +1 @@
+ALTER TABLE users ALTER COLUMN joined TYPE TIMESTAMP;
@@ -0,0 +1,2 @@
+-- Existing values are read in the session time zone.
+ALTER TABLE users ALTER COLUMN joined TYPE TIMESTAMPTZ;
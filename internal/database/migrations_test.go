@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		want     []int
+		wantFail bool
+	}{
+		{
+			name: "ordered pairs",
+			fsys: fstest.MapFS{
+				"0002_second.up.sql":   file("CREATE TABLE b ();"),
+				"0002_second.down.sql": file("DROP TABLE b;"),
+				"0001_first.up.sql":    file("CREATE TABLE a ();"),
+				"0001_first.down.sql":  file("DROP TABLE a;"),
+			},
+			want: []int{1, 2},
+		},
+		{
+			name: "missing down",
+			fsys: fstest.MapFS{
+				"0001_first.up.sql": file("CREATE TABLE a ();"),
+			},
+			wantFail: true,
+		},
+		{
+			name: "duplicate version",
+			fsys: fstest.MapFS{
+				"0001_first.up.sql":   file("CREATE TABLE a ();"),
+				"0001_first.down.sql": file("DROP TABLE a;"),
+				"0001_other.up.sql":   file("CREATE TABLE b ();"),
+				"0001_other.down.sql": file("DROP TABLE b;"),
+			},
+			wantFail: true,
+		},
+		{
+			name: "bad name",
+			fsys: fstest.MapFS{
+				"first.sql": file("CREATE TABLE a ();"),
+			},
+			wantFail: true,
+		},
+		{
+			name: "version zero",
+			fsys: fstest.MapFS{
+				"0000_first.up.sql":   file("CREATE TABLE a ();"),
+				"0000_first.down.sql": file("DROP TABLE a;"),
+			},
+			wantFail: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadMigrations(tt.fsys)
+			if tt.wantFail {
+				if err == nil {
+					t.Errorf("LoadMigrations() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadMigrations() error = %v", err)
+			}
+			var versions []int
+			for _, m := range got {
+				versions = append(versions, m.Version)
+			}
+			if fmt.Sprint(versions) != fmt.Sprint(tt.want) {
+				t.Errorf("LoadMigrations() versions = %v, want %v", versions, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Migrations() returned nothing")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %s, want version %d; versions must be consecutive", m, i+1)
+		}
+	}
+}
+
+// newTestPool connects to TEST_DATABASE_URL with a throwaway schema first on
+// the search path, so migrations can be rolled back without touching other
+// tests' tables.
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	schema := "migrate_test_" + uuid.NewString()[:8]
+	admin, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+	if _, err := admin.Exec(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.Exec(context.Background(), "DROP SCHEMA "+schema+" CASCADE")
+	})
+
+	cfg, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestMigrator(t *testing.T) {
+	pool := newTestPool(t)
+	ctx := context.Background()
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMigrator(pool, migrations)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("Up() applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second Up() = %v, %v, want nothing to do", applied, err)
+	}
+
+	rolledBack, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if len(rolledBack) != 2 || rolledBack[0].Version != len(migrations) {
+		t.Errorf("Down() rolled back %v, want the two newest", rolledBack)
+	}
+	list, err := m.Applied(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(migrations)-2 {
+		t.Errorf("Applied() = %d migrations after Down(2), want %d", len(list), len(migrations)-2)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() after Down() error = %v", err)
+	}
+
+	edited := append([]Migration(nil), migrations...)
+	edited[0].Up += "\n-- edited"
+	if _, err := NewMigrator(pool, edited).Up(ctx); err == nil {
+		t.Errorf("Up() accepted an edited migration")
+	}
+	edited = append([]Migration(nil), migrations...)
+	edited[0].Down += "\n-- edited"
+	if _, err := NewMigrator(pool, edited).Down(ctx, 1); err == nil {
+		t.Errorf("Down() accepted a migration whose down script was edited")
+	}
+}
+
+func TestMigration_Checksum(t *testing.T) {
+	m := Migration{Version: 1, Name: "first", Up: "CREATE TABLE a ();", Down: "DROP TABLE a;"}
+	edited := m
+	edited.Down = "DROP TABLE IF EXISTS a;"
+	if m.Checksum() == edited.Checksum() {
+		t.Errorf("Checksum() did not change when the down script was edited")
+	}
+}
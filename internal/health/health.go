@@ -0,0 +1,194 @@
+// Package health serves liveness and readiness probes backed by a registry
+// of dependency checks.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultTimeout  = 2 * time.Second
+	defaultCacheTTL = time.Second
+)
+
+// Check reports whether a dependency is usable. It must give up when ctx is
+// done.
+type Check func(ctx context.Context) error
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+type Result struct {
+	Name      string  `json:"name"`
+	Status    Status  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	// Error can name hosts and credentials, so it is logged but never
+	// served.
+	Error     string    `json:"-"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status       Status   `json:"status"`
+	ShuttingDown bool     `json:"shutting_down,omitempty"`
+	Checks       []Result `json:"checks"`
+}
+
+// Registry runs its checks concurrently and caches each result briefly so
+// that frequent probes don't load the dependencies. It serves the report as
+// JSON, with 503 when any check is down.
+type Registry struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+	logger   *slog.Logger
+	now      func() time.Time
+
+	mu           sync.Mutex
+	checks       []*check
+	shuttingDown atomic.Bool
+}
+
+type check struct {
+	name    string
+	fn      Check
+	timeout time.Duration
+
+	mu   sync.Mutex
+	last *Result
+}
+
+type Option func(*Registry)
+
+// WithTimeout sets the timeout for checks registered without their own.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Registry) {
+		r.timeout = d
+	}
+}
+
+// WithCacheTTL sets how long a result is reused; zero runs every check on
+// every request.
+func WithCacheTTL(d time.Duration) Option {
+	return func(r *Registry) {
+		r.cacheTTL = d
+	}
+}
+
+// WithLogger sets where failing checks are logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(r *Registry) {
+		r.logger = logger
+	}
+}
+
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		timeout:  defaultTimeout,
+		cacheTTL: defaultCacheTTL,
+		logger:   slog.Default(),
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds a check. A zero timeout uses the registry default.
+func (r *Registry) Register(name string, fn Check, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = r.timeout
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, &check{name: name, fn: fn, timeout: timeout})
+}
+
+// SetShuttingDown makes the registry report down regardless of its checks,
+// so load balancers stop routing to an instance that is draining.
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check runs every check, or reuses its cached result, and aggregates them.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.Lock()
+	checks := append([]*check(nil), r.checks...)
+	r.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status:       StatusUp,
+		ShuttingDown: r.shuttingDown.Load(),
+		Checks:       results,
+	}
+	if report.ShuttingDown {
+		report.Status = StatusDown
+	}
+	for _, res := range results {
+		if res.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, c *check) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil && r.now().Sub(c.last.CheckedAt) < r.cacheTTL {
+		return *c.last
+	}
+
+	// A probe that hangs up must not leave a "context canceled" result in
+	// the cache.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+	start := r.now()
+	err := c.fn(ctx)
+	res := Result{
+		Name:      c.name,
+		Status:    StatusUp,
+		LatencyMS: float64(r.now().Sub(start).Microseconds()) / 1000,
+		CheckedAt: start,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+		r.logger.WarnContext(ctx, "Health check failed", "check", c.name, "error", err)
+	}
+	c.last = &res
+	return res
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	report := r.Check(req.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status == StatusUp {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func up(context.Context) error { return nil }
+
+func down(context.Context) error { return errors.New("connection refused") }
+
+func TestRegistry_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		checks map[string]Check
+		want   Status
+	}{
+		{name: "no checks", checks: nil, want: StatusUp},
+		{name: "all up", checks: map[string]Check{"postgres": up, "fga": up}, want: StatusUp},
+		{name: "one down", checks: map[string]Check{"postgres": up, "fga": down}, want: StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			for name, fn := range tt.checks {
+				r.Register(name, fn, 0)
+			}
+			report := r.Check(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("Check() status = %s, want %s", report.Status, tt.want)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("Check() returned %d results, want %d", len(report.Checks), len(tt.checks))
+			}
+			for _, res := range report.Checks {
+				if (res.Error == "") != (res.Status == StatusUp) {
+					t.Errorf("result %+v: error and status disagree", res)
+				}
+			}
+		})
+	}
+}
+
+func TestRegistry_Timeout(t *testing.T) {
+	r := NewRegistry()
+	r.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, 10*time.Millisecond)
+
+	start := time.Now()
+	report := r.Check(context.Background())
+	if time.Since(start) > time.Second {
+		t.Errorf("Check() ignored the check timeout")
+	}
+	if report.Status != StatusDown || report.Checks[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Check() = %+v, want the slow check to time out", report)
+	}
+}
+
+func TestRegistry_Cache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewRegistry(WithCacheTTL(time.Second))
+	r.now = func() time.Time { return now }
+	var calls atomic.Int32
+	r.Register("postgres", func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}, 0)
+
+	r.Check(context.Background())
+	r.Check(context.Background())
+	if calls.Load() != 1 {
+		t.Errorf("check ran %d times within the cache TTL, want 1", calls.Load())
+	}
+	now = now.Add(time.Second)
+	r.Check(context.Background())
+	if calls.Load() != 2 {
+		t.Errorf("check ran %d times after the cache TTL, want 2", calls.Load())
+	}
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Register("postgres", up, 0)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != StatusUp || len(report.Checks) != 1 || report.Checks[0].Name != "postgres" {
+		t.Errorf("ServeHTTP() body = %+v", report)
+	}
+
+	r.Register("fga", func(context.Context) error { return errors.New("dial tcp fga.internal:8080: refused") }, 0)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if strings.Contains(rec.Body.String(), "fga.internal") {
+		t.Errorf("ServeHTTP() body exposes the check error: %s", rec.Body.String())
+	}
+
+	r.SetShuttingDown()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("ServeHTTP() status = %d while shutting down, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,194 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers a message, giving up once ctx is done.
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// LogSender logs who a message was for instead of delivering it. The body
+// carries one-time tokens, so it is never logged; use FileSender to read
+// mails during local development.
+type LogSender struct {
+	logger *slog.Logger
+}
+
+func NewLogSender(logger *slog.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (s *LogSender) Send(_ context.Context, m Message) error {
+	s.logger.Info("Sending mail", "to", m.To, "subject", m.Subject)
+	return nil
+}
+
+// FileSender writes every message to its own .eml file in dir.
+type FileSender struct {
+	dir string
+}
+
+func NewFileSender(dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileSender{dir: dir}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9@._-]`)
+
+func (s *FileSender) Send(_ context.Context, m Message) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(m.To, "_"))
+	err := os.WriteFile(filepath.Join(s.dir, name), format("", m), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	return nil
+}
+
+// SMTPSender delivers messages through an SMTP relay.
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPSender(addr, from, username, password string) *SMTPSender {
+	s := &SMTPSender{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTPSender) Send(ctx context.Context, m Message) error {
+	if err := s.send(ctx, m); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
+
+// send does what smtp.SendMail does, on a connection that is closed once ctx
+// is done so a stalled relay can't block the caller.
+func (s *SMTPSender) send(ctx context.Context, m Message) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(s.addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(m.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(format(s.from, m)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func format(from string, m Message) []byte {
+	var b strings.Builder
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(m.Body)
+	return []byte(b.String())
+}
+
+// Config selects the Sender: "log", "file" (writes to Dir) or "smtp". There
+// is no default, so a deployment can't silently drop its mails.
+type Config struct {
+	Sender       string `yaml:"sender"`
+	Dir          string `yaml:"dir"`
+	SMTPAddr     string `yaml:"smtp_addr"`
+	SMTPUsername string `yaml:"smtp_username"`
+	SMTPPassword string `yaml:"smtp_password"`
+	From         string `yaml:"from"`
+}
+
+func (c Config) Validate() error {
+	switch c.Sender {
+	case "":
+		return fmt.Errorf("MAIL_SENDER is required; use log, file or smtp")
+	case "log", "file":
+		return nil
+	case "smtp":
+		if c.SMTPAddr == "" || c.From == "" {
+			return fmt.Errorf("SMTP_ADDR and MAIL_FROM must be set for the smtp sender")
+		}
+		return nil
+	default:
+		return fmt.Errorf("MAIL_SENDER must be one of log, file or smtp")
+	}
+}
+
+func New(cfg Config, logger *slog.Logger) (Sender, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Sender {
+	case "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = "mail"
+		}
+		return NewFileSender(dir)
+	case "smtp":
+		return NewSMTPSender(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword), nil
+	default:
+		return NewLogSender(logger), nil
+	}
+}
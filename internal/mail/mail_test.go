@@ -0,0 +1,109 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileSender_Send(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSender(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Message{To: "user@email.test", Subject: "Hello", Body: "link: http://localhost/verify?token=abc"}
+	if err := s.Send(t.Context(), m); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), ".eml") {
+		t.Fatalf("Send() wrote %v, want a single .eml file", entries)
+	}
+	data, err := os.ReadFile(dir + "/" + entries[0].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: user@email.test", "Subject: Hello", m.Body} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("mail file does not contain %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestLogSender_Send(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewLogSender(slog.New(slog.NewTextHandler(&buf, nil)))
+	if err := s.Send(t.Context(), Message{To: "user@email.test", Subject: "Hello", Body: "secret-token"}); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "user@email.test") || !strings.Contains(buf.String(), "Hello") {
+		t.Errorf("Send() logged %q, want recipient and subject", buf.String())
+	}
+	if strings.Contains(buf.String(), "secret-token") {
+		t.Errorf("Send() logged the body: %q", buf.String())
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "unset", cfg: Config{}, wantErr: true},
+		{name: "log", cfg: Config{Sender: "log"}, wantErr: false},
+		{name: "file", cfg: Config{Sender: "file", Dir: t.TempDir()}, wantErr: false},
+		{name: "smtp", cfg: Config{Sender: "smtp", SMTPAddr: "localhost:25", From: "auth@email.test"}, wantErr: false},
+		{name: "smtp without address", cfg: Config{Sender: "smtp"}, wantErr: true},
+		{name: "unknown", cfg: Config{Sender: "pigeon"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg, slog.Default())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSMTPSender_SendTimeout(t *testing.T) {
+	// A relay that accepts the connection but never greets.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-t.Context().Done()
+	}()
+
+	s := NewSMTPSender(ln.Addr().String(), "auth@email.test", "", "")
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- s.Send(ctx, Message{To: "user@email.test", Subject: "Hello", Body: "body"}) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Send() to a stalled relay succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send() did not give up when its context expired")
+	}
+}
@@ -0,0 +1,95 @@
+// Package metrics defines the Prometheus metrics exported on /metrics. They
+// are registered with the default registry when the package is loaded.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "auth"
+
+// Authentication outcomes.
+const (
+	AuthSuccess     = "success"
+	AuthBadPassword = "bad_password"
+	AuthUnknownUser = "unknown_user"
+	AuthInactive    = "inactive"
+	AuthDisabled    = "disabled"
+	AuthError       = "error"
+)
+
+// Password hashing operations.
+const (
+	HashGenerate = "generate"
+	HashCompare  = "compare"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	Authentications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "authentications_total",
+		Help:      "Password authentication attempts by outcome.",
+	}, []string{"outcome"})
+
+	PasswordHashDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "password_hash_duration_seconds",
+		Help:      "Time spent generating and comparing bcrypt hashes.",
+		// bcrypt is deliberately slow; the default buckets would put
+		// everything into the last few.
+		Buckets: []float64{.01, .025, .05, .1, .2, .4, .8, 1.6},
+	}, []string{"op"})
+
+	TokensIssued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_issued_total",
+		Help:      "Issued tokens by type.",
+	}, []string{"type"})
+)
+
+// ObservePasswordHash records how long a bcrypt operation took since start.
+func ObservePasswordHash(op string, start time.Time) {
+	PasswordHashDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// Middleware counts and times requests by chi route pattern rather than raw
+// path so that IDs in URLs don't create a series per user. It must be
+// installed on the root router.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		HTTPRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
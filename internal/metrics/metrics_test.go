@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	for _, path := range []string{"/user/1", "/user/2", "/ok", "/missing"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	tests := []struct {
+		route  string
+		status string
+		want   float64
+	}{
+		{route: "/user/{id}", status: "404", want: 2},
+		{route: "/ok", status: "200", want: 1},
+		{route: "unmatched", status: "404", want: 1},
+	}
+	for _, tt := range tests {
+		got := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, tt.route, tt.status))
+		if got != tt.want {
+			t.Errorf("requests for %s %s = %v, want %v", tt.route, tt.status, got, tt.want)
+		}
+	}
+	if n := testutil.CollectAndCount(HTTPRequestDuration); n != 3 {
+		t.Errorf("duration series = %d, want one per route", n)
+	}
+}
+
+func TestPoolCollector(t *testing.T) {
+	// The pool connects lazily, so no database is needed to read its stats.
+	pool, err := pgxpool.New(context.Background(), "postgres://auth@localhost:1/auth?pool_max_conns=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	c := NewPoolCollector(pool)
+	if err := prometheus.NewPedanticRegistry().Register(c); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	want := `
+# HELP auth_db_pool_max_connections Maximum pool size.
+# TYPE auth_db_pool_max_connections gauge
+auth_db_pool_max_connections 7
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "auth_db_pool_max_connections"); err != nil {
+		t.Error(err)
+	}
+}
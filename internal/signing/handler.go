@@ -0,0 +1,100 @@
+package signing
+
+import (
+	"awesomeProject/internal/apperror"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type Handler struct {
+	Keys KeySource
+	// Ring is set when keys are managed by a KeyRing and enables Rotate.
+	Ring *KeyRing
+}
+
+// JWKS serves the public verification keys at /.well-known/jwks.json.
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) error {
+	keys, err := h.Keys.PublicKeys()
+	if errors.Is(err, ErrKeysUnavailable) {
+		return apperror.NewHTTPErrorWithMessage(err, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)).WithCode(apperror.CodeServiceUnavailable)
+	}
+	if err != nil {
+		return apperror.InternalServerError(err)
+	}
+	set, err := NewJWKSet(keys)
+	if err != nil {
+		return apperror.InternalServerError(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(set)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Rotate rotates the key ring. An emergency rotation retires the given key
+// (or the active one) immediately instead of letting it verify until its
+// tokens expire.
+func (h *Handler) Rotate(w http.ResponseWriter, r *http.Request) error {
+	if h.Ring == nil {
+		return apperror.NotFound(errors.New("key rotation is not enabled")).WithCode(apperror.CodeKeyRotationDisabled)
+	}
+	rr := &RotateRequest{}
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(rr)
+		if err != nil {
+			return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+		}
+	}
+	var err error
+	if rr.Emergency {
+		err = h.Ring.EmergencyRotate(rr.KeyID)
+	} else {
+		err = h.Ring.Rotate()
+	}
+	if errors.Is(err, ErrKeyNotFound) {
+		return apperror.NotFound(err).WithCode(apperror.CodeKeyNotFound)
+	}
+	if err != nil {
+		return apperror.InternalServerError(err)
+	}
+	keys := h.Ring.Keys()
+	dtos := make([]KeyDTO, 0, len(keys))
+	for _, k := range keys {
+		dtos = append(dtos, KeyDTO{
+			ID:          k.ID,
+			Algorithm:   k.Algorithm,
+			State:       k.State,
+			CreatedAt:   k.CreatedAt,
+			ActivatedAt: k.ActivatedAt,
+			RetiringAt:  k.RetiringAt,
+			RetiredAt:   k.RetiredAt,
+		})
+	}
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(dtos)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type RotateRequest struct {
+	Emergency bool   `json:"emergency"`
+	KeyID     string `json:"kid"`
+}
+
+type KeyDTO struct {
+	ID          string     `json:"kid"`
+	Algorithm   string     `json:"alg"`
+	State       State      `json:"state"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+	RetiringAt  *time.Time `json:"retiring_at,omitempty"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+}
@@ -0,0 +1,129 @@
+package signing
+
+import (
+	"awesomeProject/internal/apperror"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_JWKS(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     func(t *testing.T) KeySource
+		wantKeys int
+		wantKty  string
+	}{
+		{
+			name:     "RSA key",
+			keys:     func(t *testing.T) KeySource { return NewStaticKeys(generate(RS256)(t)) },
+			wantKeys: 1,
+			wantKty:  "RSA",
+		},
+		{
+			name:     "EC key",
+			keys:     func(t *testing.T) KeySource { return NewStaticKeys(generate(ES256)(t)) },
+			wantKeys: 1,
+			wantKty:  "EC",
+		},
+		{
+			name:     "Ed25519 key",
+			keys:     func(t *testing.T) KeySource { return NewStaticKeys(generate(EdDSA)(t)) },
+			wantKeys: 1,
+			wantKty:  "OKP",
+		},
+		{
+			name:     "HMAC secret is not published",
+			keys:     func(t *testing.T) KeySource { return NewStaticKeys(NewHMACKey("hmac", []byte("secret"))) },
+			wantKeys: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := tt.keys(t)
+			h := &Handler{Keys: keys}
+			req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+			w := httptest.NewRecorder()
+
+			err := h.JWKS(w, req)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var set JWKSet
+			err = json.NewDecoder(w.Body).Decode(&set)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Len(t, set.Keys, tt.wantKeys)
+			if tt.wantKeys > 0 {
+				k, _ := keys.SigningKey()
+				assert.Equal(t, tt.wantKty, set.Keys[0].Kty)
+				assert.Equal(t, k.ID, set.Keys[0].Kid)
+				assert.Equal(t, k.Algorithm, set.Keys[0].Alg)
+				assert.Equal(t, "sig", set.Keys[0].Use)
+			}
+		})
+	}
+}
+
+func TestHandler_JWKS_KeysUnavailable(t *testing.T) {
+	ring := newTestRing(t)
+	ring.store = failingKeyStore{NewInMemKeyStore()}
+	ring.loadedAt = time.Time{}
+	h := &Handler{Keys: ring, Ring: ring}
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	err := h.JWKS(w, req)
+	var httpError *apperror.HTTPError
+	assert.ErrorAs(t, err, &httpError)
+	assert.Equal(t, http.StatusServiceUnavailable, httpError.StatusCode)
+}
+
+func TestHandler_Rotate(t *testing.T) {
+	tests := []struct {
+		name       string
+		ring       bool
+		body       string
+		wantStatus int
+		wantCode   apperror.Code
+	}{
+		{name: "scheduled rotation", ring: true, body: "", wantStatus: http.StatusOK},
+		{name: "emergency rotation", ring: true, body: `{"emergency": true}`, wantStatus: http.StatusOK},
+		{name: "emergency rotation of unknown key", ring: true, body: `{"emergency": true, "kid": "unknown"}`, wantStatus: http.StatusNotFound, wantCode: apperror.CodeKeyNotFound},
+		{name: "invalid body", ring: true, body: `{`, wantStatus: http.StatusBadRequest, wantCode: apperror.CodeMalformedBody},
+		{name: "no key ring", ring: false, body: "", wantStatus: http.StatusNotFound, wantCode: apperror.CodeKeyRotationDisabled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{}
+			if tt.ring {
+				h.Ring = newTestRing(t)
+				h.Keys = h.Ring
+			}
+			req := httptest.NewRequest(http.MethodPost, "/admin/keys/rotate", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			err := h.Rotate(w, req)
+			if tt.wantStatus == http.StatusOK {
+				assert.NoError(t, err)
+				var keys []KeyDTO
+				err = json.NewDecoder(w.Body).Decode(&keys)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Len(t, keys, 3)
+			} else {
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantStatus, httpError.StatusCode)
+				assert.Equal(t, tt.wantCode, httpError.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// JWK is the public part of a key as described in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWKSet publishes the asymmetric keys in keys. Symmetric keys are skipped.
+func NewJWKSet(keys []*Key) (JWKSet, error) {
+	set := JWKSet{Keys: make([]JWK, 0, len(keys))}
+	for _, k := range keys {
+		if k.Symmetric() {
+			continue
+		}
+		jwk, err := k.JWK()
+		if err != nil {
+			return JWKSet{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+func (k *Key) JWK() (JWK, error) {
+	jwk := JWK{Kid: k.ID, Use: "sig", Alg: k.Algorithm}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		b, err := pub.Bytes()
+		if err != nil {
+			return JWK{}, err
+		}
+		// Uncompressed point: 0x04 || X || Y
+		size := (len(b) - 1) / 2
+		jwk.Kty = "EC"
+		jwk.Crv = "P-256"
+		jwk.X = b64(b[1 : 1+size])
+		jwk.Y = b64(b[1+size:])
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	default:
+		return JWK{}, fmt.Errorf("key %s has no public JWK representation", k.ID)
+	}
+	return jwk, nil
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint, used as the default key ID.
+func thumbprint(k *Key) (string, error) {
+	jwk, err := k.JWK()
+	if err != nil {
+		return "", err
+	}
+	// Required members only, in lexicographic order.
+	var members any
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	default:
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	}
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return b64(sum[:]), nil
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
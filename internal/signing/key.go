@@ -0,0 +1,154 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+	HS256 = "HS256"
+)
+
+// Key is a single signing key. Asymmetric keys hold a private key and publish
+// their public half through the JWKS endpoint; HS256 keys hold a shared secret
+// and are never published.
+type Key struct {
+	ID        string
+	Algorithm string
+	private   any
+	public    any
+}
+
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{
+		ID:        id,
+		Algorithm: HS256,
+		private:   secret,
+		public:    secret,
+	}
+}
+
+// GenerateKey creates a new asymmetric key for alg. The key ID is the RFC 7638
+// thumbprint of the public key.
+func GenerateKey(alg string) (*Key, error) {
+	var priv crypto.Signer
+	var err error
+	switch alg {
+	case RS256:
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EdDSA:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s key: %w", alg, err)
+	}
+	return newAsymmetricKey("", priv)
+}
+
+// ParsePrivateKeyPEM reads a PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key.
+// The algorithm is derived from the key type. If id is empty the RFC 7638
+// thumbprint is used.
+func ParsePrivateKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var priv any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		priv, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		priv, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		priv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", priv)
+	}
+	return newAsymmetricKey(id, signer)
+}
+
+func newAsymmetricKey(id string, priv crypto.Signer) (*Key, error) {
+	k := &Key{ID: id, private: priv, public: priv.Public()}
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		k.Algorithm = RS256
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 EC keys are supported")
+		}
+		k.Algorithm = ES256
+	case ed25519.PublicKey:
+		k.Algorithm = EdDSA
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
+	}
+	if k.ID == "" {
+		kid, err := thumbprint(k)
+		if err != nil {
+			return nil, err
+		}
+		k.ID = kid
+	}
+	return k, nil
+}
+
+// MarshalPrivateKeyPEM encodes an asymmetric key as PKCS#8.
+func MarshalPrivateKeyPEM(k *Key) ([]byte, error) {
+	if k.Symmetric() {
+		return nil, errors.New("symmetric keys have no PEM encoding")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(k.private)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+func (k *Key) Symmetric() bool {
+	return k.Algorithm == HS256
+}
+
+func (k *Key) Method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case RS256:
+		return jwt.SigningMethodRS256
+	case ES256:
+		return jwt.SigningMethodES256
+	case EdDSA:
+		return jwt.SigningMethodEdDSA
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// Public returns the key used to verify signatures: the public key for
+// asymmetric algorithms and the secret for HS256.
+func (k *Key) Public() any {
+	return k.public
+}
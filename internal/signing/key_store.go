@@ -0,0 +1,76 @@
+package signing
+
+import (
+	"fmt"
+	"sync"
+)
+
+type KeyStore interface {
+	List() ([]*RingKey, error)
+	Add(*RingKey) error
+	// Update persists the key's state and lifecycle timestamps.
+	Update(*RingKey) error
+	// Locked runs fn with a store whose changes are applied atomically, or
+	// not at all if fn fails. Only one Locked call runs at a time across
+	// every process sharing the store.
+	Locked(fn func(KeyStore) error) error
+}
+
+type InMemKeyStore struct {
+	// lock serializes Locked calls; mu guards keys.
+	lock sync.Mutex
+	mu   sync.Mutex
+	keys []*RingKey
+}
+
+func NewInMemKeyStore() *InMemKeyStore {
+	return &InMemKeyStore{}
+}
+
+func (s *InMemKeyStore) List() ([]*RingKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]*RingKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		c := *k
+		keys = append(keys, &c)
+	}
+	return keys, nil
+}
+
+func (s *InMemKeyStore) Add(k *RingKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *k
+	s.keys = append(s.keys, &c)
+	return nil
+}
+
+func (s *InMemKeyStore) Update(k *RingKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.keys {
+		if existing.ID == k.ID {
+			c := *k
+			s.keys[i] = &c
+			return nil
+		}
+	}
+	return fmt.Errorf("key not found")
+}
+
+// Locked runs fn on a copy of the keys and keeps the copy only if fn
+// succeeds.
+func (s *InMemKeyStore) Locked(fn func(KeyStore) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys, _ := s.List()
+	tx := &InMemKeyStore{keys: keys}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = tx.keys
+	return nil
+}
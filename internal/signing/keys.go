@@ -0,0 +1,171 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySource provides the key new tokens are signed with and the keys that may
+// still be used to verify them.
+type KeySource interface {
+	SigningKey() (*Key, error)
+	VerificationKey(kid string) (*Key, error)
+	// PublicKeys returns the keys to publish through JWKS.
+	PublicKeys() ([]*Key, error)
+}
+
+// Sign signs claims with the current signing key and sets the kid header.
+func Sign(keys KeySource, claims jwt.Claims) (string, error) {
+	k, err := keys.SigningKey()
+	if err != nil {
+		return "", err
+	}
+	t := jwt.NewWithClaims(k.Method(), claims)
+	if k.ID != "" {
+		t.Header["kid"] = k.ID
+	}
+	return t.SignedString(k.private)
+}
+
+// SigningKeyContext is keys.SigningKey, giving up once ctx is done. A key
+// ring reload it started keeps running in the background, bounded by the
+// store's own timeout.
+func SigningKeyContext(ctx context.Context, keys KeySource) (*Key, error) {
+	type result struct {
+		key *Key
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		k, err := keys.SigningKey()
+		done <- result{k, err}
+	}()
+	select {
+	case res := <-done:
+		return res.key, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Keyfunc resolves the verification key from the token's kid header and
+// rejects tokens whose alg doesn't match the key.
+func Keyfunc(keys KeySource) jwt.Keyfunc {
+	return func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		k, err := keys.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != k.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %q for key %q", t.Method.Alg(), k.ID)
+		}
+		return k.Public(), nil
+	}
+}
+
+// StaticKeys is a KeySource backed by a single key.
+type StaticKeys struct {
+	key *Key
+}
+
+func NewStaticKeys(k *Key) *StaticKeys {
+	return &StaticKeys{key: k}
+}
+
+func (s *StaticKeys) SigningKey() (*Key, error) {
+	return s.key, nil
+}
+
+func (s *StaticKeys) VerificationKey(kid string) (*Key, error) {
+	if kid != "" && kid != s.key.ID {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return s.key, nil
+}
+
+func (s *StaticKeys) PublicKeys() ([]*Key, error) {
+	return []*Key{s.key}, nil
+}
+
+// Config describes where signing keys come from:
+//
+//   - KeyFile: PEM encoded private key; the algorithm follows the key type
+//   - KeyID: optional kid, defaults to the key's RFC 7638 thumbprint
+//   - Alg: RS256, ES256, EdDSA or HS256 (default)
+//   - Key: the shared secret when Alg is HS256
+//   - RotateEvery, RetireAfter: key ring schedule, see KeyRing
+//
+// An asymmetric Alg without a key file gives a KeyRing.
+type Config struct {
+	Alg         string        `yaml:"alg"`
+	Key         string        `yaml:"key"`
+	KeyFile     string        `yaml:"key_file"`
+	KeyID       string        `yaml:"key_id"`
+	RotateEvery time.Duration `yaml:"rotate_every"`
+	RetireAfter time.Duration `yaml:"retire_after"`
+}
+
+// DefaultConfig rotates keys every 30 days and keeps retiring keys for an
+// hour.
+func DefaultConfig() Config {
+	return Config{
+		RotateEvery: 30 * 24 * time.Hour,
+		RetireAfter: time.Hour,
+	}
+}
+
+// Validate checks the settings without touching the key file or store.
+func (c Config) Validate() []error {
+	var errs []error
+	switch c.Alg {
+	case "", HS256:
+		if c.KeyFile == "" && c.Key == "" {
+			errs = append(errs, errors.New("SIGN_KEY is required for HS256 signing"))
+		}
+	case RS256, ES256, EdDSA:
+		if c.KeyFile == "" && (c.RotateEvery <= 0 || c.RetireAfter <= 0) {
+			errs = append(errs, errors.New("SIGN_ROTATE_EVERY and SIGN_RETIRE_AFTER must be positive"))
+		}
+	default:
+		errs = append(errs, errors.New("SIGN_ALG must be one of RS256, ES256, EdDSA or HS256"))
+	}
+	return errs
+}
+
+// New builds the KeySource described by cfg, keeping a KeyRing in store.
+func New(cfg Config, store KeyStore, logger *slog.Logger) (KeySource, error) {
+	if errs := cfg.Validate(); len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if cfg.KeyFile != "" {
+		data, err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		k, err := ParsePrivateKeyPEM(cfg.KeyID, data)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Alg != "" && cfg.Alg != k.Algorithm {
+			return nil, fmt.Errorf("SIGN_ALG is %s but SIGN_KEY_FILE holds an %s key", cfg.Alg, k.Algorithm)
+		}
+		return NewStaticKeys(k), nil
+	}
+	switch cfg.Alg {
+	case "", HS256:
+		return NewStaticKeys(NewHMACKey("", []byte(cfg.Key))), nil
+	default:
+		ring := NewKeyRing(store, cfg.Alg, cfg.RotateEvery, cfg.RetireAfter, logger)
+		if err := ring.Load(); err != nil {
+			return nil, fmt.Errorf("failed to load key ring: %w", err)
+		}
+		return ring, nil
+	}
+}
@@ -0,0 +1,184 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSignAndKeyfunc(t *testing.T) {
+	tests := []struct {
+		name string
+		key  func(t *testing.T) *Key
+	}{
+		{name: "RS256", key: generate(RS256)},
+		{name: "ES256", key: generate(ES256)},
+		{name: "EdDSA", key: generate(EdDSA)},
+		{name: "HS256", key: func(t *testing.T) *Key { return NewHMACKey("hmac", []byte("secret")) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := tt.key(t)
+			keys := NewStaticKeys(k)
+			signed, err := Sign(keys, jwt.RegisteredClaims{
+				Subject:   "subject",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			})
+			if err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+
+			var claims jwt.RegisteredClaims
+			token, err := jwt.ParseWithClaims(signed, &claims, Keyfunc(keys))
+			if err != nil {
+				t.Fatalf("ParseWithClaims() error = %v", err)
+			}
+			if token.Header["kid"] != k.ID {
+				t.Errorf("kid = %v, want %v", token.Header["kid"], k.ID)
+			}
+			if token.Method.Alg() != tt.name {
+				t.Errorf("alg = %v, want %v", token.Method.Alg(), tt.name)
+			}
+			if claims.Subject != "subject" {
+				t.Errorf("sub = %v, want subject", claims.Subject)
+			}
+		})
+	}
+}
+
+// stalledKeys is a KeySource whose SigningKey blocks until release is
+// closed, like a ring rereading its keys from an unresponsive database.
+type stalledKeys struct {
+	*StaticKeys
+	release chan struct{}
+}
+
+func (s stalledKeys) SigningKey() (*Key, error) {
+	<-s.release
+	return s.StaticKeys.SigningKey()
+}
+
+func TestSigningKeyContext(t *testing.T) {
+	k := NewHMACKey("hmac", []byte("secret"))
+	got, err := SigningKeyContext(t.Context(), NewStaticKeys(k))
+	if err != nil || got != k {
+		t.Fatalf("SigningKeyContext() = %v, %v, want %v", got, err, k)
+	}
+
+	keys := stalledKeys{StaticKeys: NewStaticKeys(k), release: make(chan struct{})}
+	defer close(keys.release)
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := SigningKeyContext(ctx, keys); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SigningKeyContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestKeyfunc_RejectsUnknownKidAndAlgMismatch(t *testing.T) {
+	signer := NewStaticKeys(generate(ES256)(t))
+	signed, err := Sign(signer, jwt.RegisteredClaims{Subject: "subject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := NewStaticKeys(generate(ES256)(t))
+	if _, err := jwt.Parse(signed, Keyfunc(other)); err == nil {
+		t.Errorf("Parse() should reject a token signed by an unknown kid")
+	}
+
+	// An HS256 token claiming the kid of an asymmetric key must not be
+	// verified with the public key as HMAC secret.
+	k, _ := signer.SigningKey()
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "subject"})
+	forged.Header["kid"] = k.ID
+	forgedString, err := forged.SignedString([]byte("guess"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(forgedString, Keyfunc(signer)); err == nil {
+		t.Errorf("Parse() should reject a token whose alg doesn't match the key")
+	}
+}
+
+func TestParsePrivateKeyPEM_RoundTrip(t *testing.T) {
+	for _, alg := range []string{RS256, ES256, EdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			k := generate(alg)(t)
+			data, err := MarshalPrivateKeyPEM(k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParsePrivateKeyPEM("", data)
+			if err != nil {
+				t.Fatalf("ParsePrivateKeyPEM() error = %v", err)
+			}
+			if got.ID != k.ID || got.Algorithm != k.Algorithm {
+				t.Errorf("ParsePrivateKeyPEM() = %s/%s, want %s/%s", got.ID, got.Algorithm, k.ID, k.Algorithm)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	k := generate(ES256)(t)
+	data, err := MarshalPrivateKeyPEM(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantAlg string
+		wantErr bool
+	}{
+		{name: "hmac", cfg: Config{Key: "secret"}, wantAlg: HS256},
+		{name: "hmac without secret", cfg: Config{}, wantErr: true},
+		{name: "key file", cfg: Config{KeyFile: keyFile}, wantAlg: ES256},
+		{name: "key file with other alg", cfg: Config{KeyFile: keyFile, Alg: RS256}, wantErr: true},
+		{name: "missing key file", cfg: Config{KeyFile: keyFile + ".missing"}, wantErr: true},
+		{name: "key ring", cfg: Config{Alg: EdDSA, RotateEvery: time.Hour, RetireAfter: time.Minute}, wantAlg: EdDSA},
+		{name: "key ring without schedule", cfg: Config{Alg: EdDSA}, wantErr: true},
+		{name: "unknown alg", cfg: Config{Alg: "none"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := New(tt.cfg, NewInMemKeyStore(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			sk, err := keys.SigningKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sk.Algorithm != tt.wantAlg {
+				t.Errorf("New() signs with %s, want %s", sk.Algorithm, tt.wantAlg)
+			}
+		})
+	}
+}
+
+func generate(alg string) func(t *testing.T) *Key {
+	return func(t *testing.T) *Key {
+		t.Helper()
+		k, err := GenerateKey(alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+}
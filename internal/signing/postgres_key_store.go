@@ -0,0 +1,159 @@
+package signing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// keyRingLockID is the advisory lock held by every key ring transition.
+const keyRingLockID = 0x6b657972696e67
+
+// queryTimeout bounds every query, and transitionTimeout a whole transition
+// including the wait for the lock, so a stuck database can't hang signing or
+// the rotation job.
+const (
+	queryTimeout      = 5 * time.Second
+	transitionTimeout = 30 * time.Second
+)
+
+// querier is satisfied by both the pool and a transaction.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type PostgresKeyStore struct {
+	pool *pgxpool.Pool
+	// db is the pool, or the transaction of a store passed to Locked.
+	db querier
+}
+
+func NewPostgresKeyStore(pool *pgxpool.Pool) *PostgresKeyStore {
+	return &PostgresKeyStore{
+		pool: pool,
+		db:   pool,
+	}
+}
+
+func (s *PostgresKeyStore) List() ([]*RingKey, error) {
+	query := `
+		SELECT id, private_key, state, created_at, activated_at, retiring_at, retired_at
+		FROM signing_keys
+		ORDER BY created_at
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*RingKey, 0)
+	for rows.Next() {
+		var k RingKey
+		var id string
+		var privateKey []byte
+		err := rows.Scan(&id, &privateKey, &k.State, &k.CreatedAt, &k.ActivatedAt, &k.RetiringAt, &k.RetiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan signing key: %w", err)
+		}
+		k.Key, err = ParsePrivateKeyPEM(id, privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s: %w", id, err)
+		}
+		keys = append(keys, &k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (s *PostgresKeyStore) Add(k *RingKey) error {
+	query := `
+		INSERT INTO signing_keys (id, algorithm, private_key, state, created_at, activated_at, retiring_at, retired_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	privateKey, err := MarshalPrivateKeyPEM(k.Key)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	_, err = s.db.Exec(
+		ctx,
+		query,
+		k.ID,
+		k.Algorithm,
+		privateKey,
+		k.State,
+		k.CreatedAt,
+		k.ActivatedAt,
+		k.RetiringAt,
+		k.RetiredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add signing key: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresKeyStore) Update(k *RingKey) error {
+	query := `
+		UPDATE signing_keys
+		SET state = $2, activated_at = $3, retiring_at = $4, retired_at = $5
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	tag, err := s.db.Exec(
+		ctx,
+		query,
+		k.ID,
+		k.State,
+		k.ActivatedAt,
+		k.RetiringAt,
+		k.RetiredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update signing key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("key not found")
+	}
+
+	return nil
+}
+
+// Locked runs fn in a transaction holding a transaction-level advisory lock,
+// so transitions on different replicas run one after another and each sees
+// the keys the previous one committed.
+func (s *PostgresKeyStore) Locked(fn func(KeyStore) error) error {
+	if s.db != s.pool {
+		return fn(s)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), transitionTimeout)
+	defer cancel()
+	return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, keyRingLockID); err != nil {
+			return fmt.Errorf("failed to lock signing keys: %w", err)
+		}
+		return fn(&PostgresKeyStore{pool: s.pool, db: tx})
+	})
+}
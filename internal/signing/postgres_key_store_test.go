@@ -0,0 +1,127 @@
+package signing
+
+import (
+	"awesomeProject/internal/database"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newTestStore connects to the database in TEST_DATABASE_URL, runs the
+// migrations and empties signing_keys. Tests using it are skipped when the
+// variable is unset.
+func newTestStore(t *testing.T) *PostgresKeyStore {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	if err := database.RunMigrations(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	empty := func() {
+		if _, err := pool.Exec(context.Background(), `DELETE FROM signing_keys`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	empty()
+	t.Cleanup(empty)
+	return NewPostgresKeyStore(pool)
+}
+
+func TestPostgresKeyStore_LockedRollsBack(t *testing.T) {
+	store := newTestStore(t)
+	k, err := GenerateKey(ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err = store.Locked(func(tx KeyStore) error {
+		if err := tx.Add(&RingKey{Key: k, State: StatePending, CreatedAt: time.Now()}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Locked() error = %v, want %v", err, failed)
+	}
+	keys, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("Locked() kept %d keys added by a failed transaction", len(keys))
+	}
+}
+
+func TestPostgresKeyStore_ConcurrentTransitions(t *testing.T) {
+	store := newTestStore(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Each replica has its own store sharing the pool, as separate processes
+	// would share the database.
+	var replicas []*KeyRing
+	for range 4 {
+		replicas = append(replicas, NewKeyRing(NewPostgresKeyStore(store.pool), ES256, time.Hour, time.Minute, logger))
+	}
+	var wg sync.WaitGroup
+	for _, r := range replicas {
+		wg.Go(func() {
+			if err := r.Load(); err != nil {
+				t.Errorf("Load() error = %v", err)
+				return
+			}
+			if err := r.Rotate(); err != nil {
+				t.Errorf("Rotate() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	keys, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[State]int)
+	for _, k := range keys {
+		got[k.State]++
+	}
+	if got[StateActive] != 1 || got[StatePending] != 1 || got[StateRetiring] != len(replicas) {
+		t.Errorf("concurrent Load() and Rotate() states = %v, want one active, one pending and %d retiring keys", got, len(replicas))
+	}
+}
+
+func TestPostgresKeyStore_Times(t *testing.T) {
+	local := time.Local
+	time.Local = time.FixedZone("UTC-7", -7*60*60)
+	t.Cleanup(func() { time.Local = local })
+	store := newTestStore(t)
+
+	ring := NewKeyRing(store, ES256, time.Hour, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := ring.Load(); err != nil {
+		t.Fatal(err)
+	}
+	// A key activated a moment ago must not look hours old or hours ahead.
+	if err := ring.tick(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range ring.Keys() {
+		if k.State == StateRetiring {
+			t.Errorf("tick() rotated a key activated moments ago")
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var ErrKeyNotFound = errors.New("key not found")
+
+type State string
+
+const (
+	// StatePending keys are published in JWKS but not used for signing yet,
+	// so verifiers can cache them before the first token appears.
+	StatePending State = "pending"
+	// StateActive is the single key new tokens are signed with.
+	StateActive State = "active"
+	// StateRetiring keys no longer sign but still verify outstanding tokens.
+	StateRetiring State = "retiring"
+	// StateRetired keys are neither published nor accepted.
+	StateRetired State = "retired"
+)
+
+// RingKey is a key managed by a KeyRing together with its lifecycle.
+type RingKey struct {
+	*Key
+	State       State
+	CreatedAt   time.Time
+	ActivatedAt *time.Time
+	RetiringAt  *time.Time
+	RetiredAt   *time.Time
+}
+
+// ErrKeysUnavailable is returned when the keys can't be read from the store.
+// It means a token couldn't be checked, not that it is invalid.
+var ErrKeysUnavailable = errors.New("signing keys unavailable")
+
+// keyStateTTL is how long the ring trusts the key states it last read. A key
+// retired on another replica is still accepted here for at most this long.
+const keyStateTTL = 5 * time.Second
+
+// KeyRing is a KeySource holding several keys in different states. Keys are
+// rotated pending -> active -> retiring -> retired, either on schedule by Run
+// or on demand by Rotate and EmergencyRotate.
+//
+// Replicas share the keys through the store. Every transition reads and
+// writes them inside KeyStore.Locked, so two replicas never rotate at once.
+// Signing and verifying reread the keys once they are older than keyStateTTL,
+// so a rotation on one replica reaches the others within that time.
+type KeyRing struct {
+	store KeyStore
+	alg   string
+	// rotateEvery is how long a key stays active.
+	rotateEvery time.Duration
+	// retireAfter is how long a retiring key keeps verifying. It must be at
+	// least the lifetime of the tokens it signed.
+	retireAfter time.Duration
+	// stateTTL is how long loaded keys are used before they are reread.
+	stateTTL time.Duration
+	logger   *slog.Logger
+
+	// reloadMu lets a single caller reread stale keys while the others wait
+	// for its result.
+	reloadMu sync.Mutex
+
+	mu       sync.RWMutex
+	keys     []*RingKey
+	loadedAt time.Time
+}
+
+func NewKeyRing(store KeyStore, alg string, rotateEvery, retireAfter time.Duration, logger *slog.Logger) *KeyRing {
+	return &KeyRing{
+		store:       store,
+		alg:         alg,
+		rotateEvery: rotateEvery,
+		retireAfter: retireAfter,
+		stateTTL:    keyStateTTL,
+		logger:      logger,
+	}
+}
+
+// Load reads the keys from the store, creating an active and a pending key if
+// the store has no active key yet.
+func (r *KeyRing) Load() error {
+	return r.transition(func(*ringTx) error { return nil })
+}
+
+// transition loads the keys under the store lock, lets fn change them and
+// adopts the result once it is committed. Loading already bootstraps a store
+// without an active key.
+func (r *KeyRing) transition(fn func(*ringTx) error) error {
+	var keys []*RingKey
+	err := r.store.Locked(func(store KeyStore) error {
+		tx := &ringTx{ring: r, store: store}
+		if err := tx.load(); err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		keys = tx.keys
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.setKeys(keys)
+	return nil
+}
+
+// current returns the keys, rereading them from the store first if they are
+// older than stateTTL.
+func (r *KeyRing) current() ([]*RingKey, error) {
+	if keys, ok := r.fresh(); ok {
+		return keys, nil
+	}
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	if keys, ok := r.fresh(); ok {
+		return keys, nil
+	}
+	keys, err := r.store.List()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+	r.setKeys(keys)
+	return keys, nil
+}
+
+func (r *KeyRing) fresh() ([]*RingKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.keys, time.Since(r.loadedAt) < r.stateTTL
+}
+
+func (r *KeyRing) setKeys(keys []*RingKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = keys
+	r.loadedAt = time.Now()
+}
+
+func (r *KeyRing) SigningKey() (*Key, error) {
+	keys, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	if k := find(keys, StateActive); k != nil {
+		return k.Key, nil
+	}
+	return nil, fmt.Errorf("no active signing key")
+}
+
+func (r *KeyRing) VerificationKey(kid string) (*Key, error) {
+	keys, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.ID != kid {
+			continue
+		}
+		if k.State != StateActive && k.State != StateRetiring {
+			return nil, fmt.Errorf("key %q is %s", kid, k.State)
+		}
+		return k.Key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// PublicKeys returns the pending, active and retiring keys, rereading them
+// like VerificationKey so keys created or retired by other replicas are seen.
+func (r *KeyRing) PublicKeys() ([]*Key, error) {
+	current, err := r.current()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*Key, 0, len(current))
+	for _, k := range current {
+		if k.State != StateRetired {
+			keys = append(keys, k.Key)
+		}
+	}
+	return keys, nil
+}
+
+// Keys returns a snapshot of every key the ring knows about.
+func (r *KeyRing) Keys() []RingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	keys := make([]RingKey, 0, len(r.keys))
+	for _, k := range r.keys {
+		keys = append(keys, *k)
+	}
+	return keys
+}
+
+// Rotate moves the active key to retiring, promotes the pending key and
+// prepares a new pending key.
+func (r *KeyRing) Rotate() error {
+	return r.transition(func(tx *ringTx) error {
+		return tx.rotate(time.Now())
+	})
+}
+
+// EmergencyRotate retires the key kid immediately, or the active key if kid
+// is empty. Tokens signed by it are rejected from then on. If it was the
+// active key a replacement is promoted straight away.
+func (r *KeyRing) EmergencyRotate(kid string) error {
+	return r.transition(func(tx *ringTx) error {
+		now := time.Now()
+		var compromised *RingKey
+		for _, k := range tx.keys {
+			if (kid == "" && k.State == StateActive) || (kid != "" && k.ID == kid) {
+				compromised = k
+				break
+			}
+		}
+		if compromised == nil {
+			return fmt.Errorf("%w: %q", ErrKeyNotFound, kid)
+		}
+		wasActive := compromised.State == StateActive
+		if err := tx.setState(compromised, StateRetired, now); err != nil {
+			return err
+		}
+		if wasActive {
+			if err := tx.promote(now); err != nil {
+				return err
+			}
+		}
+		return tx.ensurePending(now)
+	})
+}
+
+// Run rotates keys on schedule until ctx is cancelled. Every tick the ring is
+// reloaded from the store, so rotations done by other replicas are picked up.
+func (r *KeyRing) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.tick(time.Now()); err != nil {
+				r.logger.Error("Failed to rotate signing keys", "error", err)
+			}
+		}
+	}
+}
+
+func (r *KeyRing) tick(now time.Time) error {
+	return r.transition(func(tx *ringTx) error {
+		active := find(tx.keys, StateActive)
+		if active.ActivatedAt != nil && !now.Before(active.ActivatedAt.Add(r.rotateEvery)) {
+			r.logger.Info("Rotating signing key", "kid", active.ID)
+			return tx.rotate(now)
+		}
+		return tx.retireExpired(now)
+	})
+}
+
+// ringTx is a transition in progress: the keys as read from the locked store
+// and the changes made to them so far.
+type ringTx struct {
+	ring  *KeyRing
+	store KeyStore
+	keys  []*RingKey
+}
+
+func (tx *ringTx) load() error {
+	keys, err := tx.store.List()
+	if err != nil {
+		return err
+	}
+	tx.keys = keys
+	if find(tx.keys, StateActive) != nil {
+		return nil
+	}
+	now := time.Now()
+	if err := tx.promote(now); err != nil {
+		return err
+	}
+	return tx.ensurePending(now)
+}
+
+func (tx *ringTx) rotate(now time.Time) error {
+	if active := find(tx.keys, StateActive); active != nil {
+		if err := tx.setState(active, StateRetiring, now); err != nil {
+			return err
+		}
+	}
+	if err := tx.promote(now); err != nil {
+		return err
+	}
+	if err := tx.ensurePending(now); err != nil {
+		return err
+	}
+	return tx.retireExpired(now)
+}
+
+// promote makes the pending key active, generating one if there is none.
+func (tx *ringTx) promote(now time.Time) error {
+	pending := find(tx.keys, StatePending)
+	if pending == nil {
+		k, err := tx.generate(now)
+		if err != nil {
+			return err
+		}
+		pending = k
+	}
+	return tx.setState(pending, StateActive, now)
+}
+
+func (tx *ringTx) ensurePending(now time.Time) error {
+	if find(tx.keys, StatePending) != nil {
+		return nil
+	}
+	_, err := tx.generate(now)
+	return err
+}
+
+func (tx *ringTx) retireExpired(now time.Time) error {
+	for _, k := range tx.keys {
+		if k.State == StateRetiring && k.RetiringAt != nil && !now.Before(k.RetiringAt.Add(tx.ring.retireAfter)) {
+			if err := tx.setState(k, StateRetired, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (tx *ringTx) generate(now time.Time) (*RingKey, error) {
+	k, err := GenerateKey(tx.ring.alg)
+	if err != nil {
+		return nil, err
+	}
+	rk := &RingKey{Key: k, State: StatePending, CreatedAt: now}
+	if err := tx.store.Add(rk); err != nil {
+		return nil, err
+	}
+	tx.keys = append(tx.keys, rk)
+	return rk, nil
+}
+
+func (tx *ringTx) setState(k *RingKey, state State, now time.Time) error {
+	updated := *k
+	updated.State = state
+	switch state {
+	case StateActive:
+		updated.ActivatedAt = &now
+	case StateRetiring:
+		updated.RetiringAt = &now
+	case StateRetired:
+		updated.RetiredAt = &now
+	}
+	if err := tx.store.Update(&updated); err != nil {
+		return err
+	}
+	*k = updated
+	return nil
+}
+
+func find(keys []*RingKey, state State) *RingKey {
+	for _, k := range keys {
+		if k.State == state {
+			return k
+		}
+	}
+	return nil
+}
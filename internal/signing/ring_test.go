@@ -0,0 +1,252 @@
+package signing
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestRing(t *testing.T) *KeyRing {
+	t.Helper()
+	ring := NewKeyRing(NewInMemKeyStore(), ES256, time.Hour, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := ring.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return ring
+}
+
+func statesOf(ring *KeyRing) map[State]int {
+	states := make(map[State]int)
+	for _, k := range ring.Keys() {
+		states[k.State]++
+	}
+	return states
+}
+
+func TestKeyRing_LoadBootstrapsKeys(t *testing.T) {
+	ring := newTestRing(t)
+	got := statesOf(ring)
+	if got[StateActive] != 1 || got[StatePending] != 1 || len(ring.Keys()) != 2 {
+		t.Fatalf("Load() states = %v, want one active and one pending key", got)
+	}
+
+	// A second ring on the same store must reuse the persisted keys.
+	other := NewKeyRing(ring.store, ES256, time.Hour, time.Minute, ring.logger)
+	if err := other.Load(); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := ring.SigningKey()
+	b, _ := other.SigningKey()
+	if a.ID != b.ID {
+		t.Errorf("rings on the same store sign with different keys: %s and %s", a.ID, b.ID)
+	}
+}
+
+func TestKeyRing_Rotate(t *testing.T) {
+	ring := newTestRing(t)
+	oldActive, _ := ring.SigningKey()
+	var pending *Key
+	for _, k := range ring.Keys() {
+		if k.State == StatePending {
+			pending = k.Key
+		}
+	}
+	signed, err := Sign(ring, jwt.RegisteredClaims{Subject: "subject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ring.Rotate(); err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	newActive, _ := ring.SigningKey()
+	if newActive.ID != pending.ID {
+		t.Errorf("Rotate() activated %s, want the pending key %s", newActive.ID, pending.ID)
+	}
+	got := statesOf(ring)
+	if got[StateActive] != 1 || got[StatePending] != 1 || got[StateRetiring] != 1 {
+		t.Errorf("Rotate() states = %v, want one active, pending and retiring key", got)
+	}
+	if _, err := jwt.Parse(signed, Keyfunc(ring)); err != nil {
+		t.Errorf("token signed by the retiring key %s should still verify: %v", oldActive.ID, err)
+	}
+	if published, err := ring.PublicKeys(); err != nil || len(published) != 3 {
+		t.Errorf("PublicKeys() = %d keys, %v, want pending, active and retiring", len(published), err)
+	}
+}
+
+func TestKeyRing_Tick(t *testing.T) {
+	ring := newTestRing(t)
+	first, _ := ring.SigningKey()
+	signed, err := Sign(ring, jwt.RegisteredClaims{Subject: "subject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if err := ring.tick(now.Add(30 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if k, _ := ring.SigningKey(); k.ID != first.ID {
+		t.Fatalf("tick() rotated before the key was due")
+	}
+
+	if err := ring.tick(now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if k, _ := ring.SigningKey(); k.ID == first.ID {
+		t.Fatalf("tick() did not rotate a key that was due")
+	}
+	if _, err := jwt.Parse(signed, Keyfunc(ring)); err != nil {
+		t.Errorf("retiring key should verify within the retire window: %v", err)
+	}
+
+	if err := ring.tick(now.Add(time.Hour + 2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jwt.Parse(signed, Keyfunc(ring)); err == nil {
+		t.Errorf("retired key should no longer verify")
+	}
+	published, err := ring.PublicKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range published {
+		if k.ID == first.ID {
+			t.Errorf("retired key %s is still published", k.ID)
+		}
+	}
+}
+
+func TestKeyRing_EmergencyRotate(t *testing.T) {
+	ring := newTestRing(t)
+	compromised, _ := ring.SigningKey()
+	signed, err := Sign(ring, jwt.RegisteredClaims{Subject: "subject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ring.EmergencyRotate(""); err != nil {
+		t.Fatalf("EmergencyRotate() error = %v", err)
+	}
+
+	if k, _ := ring.SigningKey(); k.ID == compromised.ID {
+		t.Errorf("EmergencyRotate() kept signing with the compromised key")
+	}
+	if _, err := jwt.Parse(signed, Keyfunc(ring)); err == nil {
+		t.Errorf("token signed by the compromised key should be rejected immediately")
+	}
+	got := statesOf(ring)
+	if got[StateActive] != 1 || got[StatePending] != 1 || got[StateRetired] != 1 {
+		t.Errorf("EmergencyRotate() states = %v, want one active, pending and retired key", got)
+	}
+
+	if err := ring.EmergencyRotate("unknown"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("EmergencyRotate(unknown) error = %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestKeyRing_EmergencyRotateOnOtherReplica(t *testing.T) {
+	ring := newTestRing(t)
+	other := NewKeyRing(ring.store, ES256, time.Hour, time.Minute, ring.logger)
+	if err := other.Load(); err != nil {
+		t.Fatal(err)
+	}
+	compromised, _ := other.SigningKey()
+	signed, err := Sign(other, jwt.RegisteredClaims{Subject: "subject"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ring.EmergencyRotate(compromised.ID); err != nil {
+		t.Fatalf("EmergencyRotate() error = %v", err)
+	}
+
+	// The other replica keeps its loaded keys until they are stateTTL old.
+	if _, err := jwt.Parse(signed, Keyfunc(other)); err != nil {
+		t.Fatalf("other replica rejected a token before its keys went stale: %v", err)
+	}
+	other.mu.Lock()
+	other.loadedAt = other.loadedAt.Add(-other.stateTTL)
+	other.mu.Unlock()
+
+	published, err := other.PublicKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := ring.PublicKeys()
+	if len(published) != len(want) {
+		t.Errorf("other replica PublicKeys() = %d keys, want the %d published by the rotating replica", len(published), len(want))
+	}
+	for _, k := range published {
+		if k.ID == compromised.ID {
+			t.Errorf("other replica still publishes the compromised key %s", k.ID)
+		}
+	}
+
+	if _, err := jwt.Parse(signed, Keyfunc(other)); err == nil {
+		t.Errorf("other replica should reject the compromised key once its keys are stale")
+	}
+	if k, err := other.SigningKey(); err != nil || k.ID == compromised.ID {
+		t.Errorf("other replica SigningKey() = %v, %v, want the replacement key", k, err)
+	}
+}
+
+func TestKeyRing_ConcurrentRotate(t *testing.T) {
+	ring := newTestRing(t)
+	replicas := []*KeyRing{ring}
+	for range 3 {
+		replicas = append(replicas, NewKeyRing(ring.store, ES256, time.Hour, time.Minute, ring.logger))
+	}
+
+	var wg sync.WaitGroup
+	for _, r := range replicas {
+		wg.Go(func() {
+			if err := r.Rotate(); err != nil {
+				t.Errorf("Rotate() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	keys, _ := ring.store.List()
+	got := make(map[State]int)
+	for _, k := range keys {
+		got[k.State]++
+	}
+	if got[StateActive] != 1 || got[StatePending] != 1 || got[StateRetiring] != len(replicas) {
+		t.Errorf("concurrent Rotate() states = %v, want one active, one pending and %d retiring keys", got, len(replicas))
+	}
+}
+
+// failingKeyStore fails every read, as a store whose database is down.
+type failingKeyStore struct {
+	*InMemKeyStore
+}
+
+func (failingKeyStore) List() ([]*RingKey, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestKeyRing_StoreUnavailable(t *testing.T) {
+	ring := newTestRing(t)
+	kid := ring.Keys()[0].ID
+	ring.store = failingKeyStore{NewInMemKeyStore()}
+	ring.loadedAt = time.Time{}
+
+	if _, err := ring.VerificationKey(kid); !errors.Is(err, ErrKeysUnavailable) {
+		t.Errorf("VerificationKey() error = %v, want ErrKeysUnavailable", err)
+	}
+	if _, err := ring.SigningKey(); !errors.Is(err, ErrKeysUnavailable) {
+		t.Errorf("SigningKey() error = %v, want ErrKeysUnavailable", err)
+	}
+	if _, err := ring.PublicKeys(); !errors.Is(err, ErrKeysUnavailable) {
+		t.Errorf("PublicKeys() error = %v, want ErrKeysUnavailable", err)
+	}
+}
@@ -0,0 +1,47 @@
+package telemetry
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span per request, continuing the caller's trace
+// when it sends W3C trace context headers. Once routing is done the span is
+// named after the chi route pattern. It must be installed on the root
+// router.
+func Middleware(next http.Handler) http.Handler {
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if pattern := routePattern(r); pattern != "" {
+			trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(pattern))
+		}
+	})
+	return otelhttp.NewHandler(routed, "http.server", otelhttp.WithSpanNameFormatter(spanName))
+}
+
+// spanName is called when the request arrives and again once chi has matched
+// a route.
+func spanName(_ string, r *http.Request) string {
+	if pattern := routePattern(r); pattern != "" {
+		return r.Method + " " + pattern
+	}
+	return r.Method
+}
+
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+	return rctx.RoutePattern()
+}
+
+// Transport wraps base so outbound requests get a client span and carry the
+// trace context.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
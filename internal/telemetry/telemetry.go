@@ -0,0 +1,94 @@
+// Package telemetry sets up OpenTelemetry tracing and the instrumentation
+// shared by the HTTP server, the service and the database layer.
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope used for the service's own spans.
+const ScopeName = "awesomeProject"
+
+// Tracer returns the tracer for the service's own spans. It follows the
+// global provider, so it can be taken before Setup runs.
+func Tracer() trace.Tracer {
+	return otel.Tracer(ScopeName)
+}
+
+type Config struct {
+	// Exporter is "none", "stdout" or "otlp". Defaults to "none".
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP/HTTP collector URL, e.g. http://localhost:4318.
+	// Defaults to the exporter's own default.
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Exporter:    "none",
+		ServiceName: "auth-service",
+		SampleRatio: 1,
+	}
+}
+
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case "", "none", "stdout", "otlp":
+	default:
+		return fmt.Errorf("unknown trace exporter %q", c.Exporter)
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Setup installs the W3C trace context propagator and, unless the exporter
+// is "none", a tracer provider. The returned function flushes and stops the
+// provider.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
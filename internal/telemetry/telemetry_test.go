@@ -0,0 +1,116 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a provider that keeps ended spans in memory.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{name: "default", cfg: DefaultConfig(), wantErr: false},
+		{name: "otlp", cfg: Config{Exporter: "otlp", SampleRatio: 0.1}, wantErr: false},
+		{name: "unknown exporter", cfg: Config{Exporter: "jaeger"}, wantErr: true},
+		{name: "ratio above 1", cfg: Config{Exporter: "stdout", SampleRatio: 2}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	recordSpans(t)
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewTextHandler(&buf, nil))).With("component", "test")
+
+	logger.InfoContext(context.Background(), "no span")
+	if strings.Contains(buf.String(), "trace_id") {
+		t.Errorf("logged a trace ID without a span: %s", buf.String())
+	}
+
+	buf.Reset()
+	ctx, span := Tracer().Start(context.Background(), "op")
+	defer span.End()
+	logger.InfoContext(ctx, "in span")
+	want := "trace_id=" + span.SpanContext().TraceID().String()
+	if !strings.Contains(buf.String(), want) || !strings.Contains(buf.String(), "component=test") {
+		t.Errorf("logged %q, want %s and the logger attributes", buf.String(), want)
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	rec := recordSpans(t)
+	var tracer QueryTracer
+
+	ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "\n\tselect id FROM users WHERE id = $1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+	ctx = tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "UPDATE users SET name = $1"})
+	tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: errors.New("duplicate key")})
+
+	spans := rec.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	if spans[0].Name() != "SELECT" || spans[0].Status().Code == codes.Error {
+		t.Errorf("first span = %s %v, want SELECT without error", spans[0].Name(), spans[0].Status())
+	}
+	if spans[1].Name() != "UPDATE" || spans[1].Status().Code != codes.Error {
+		t.Errorf("second span = %s %v, want UPDATE with error", spans[1].Name(), spans[1].Status())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	rec := recordSpans(t)
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/user/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/user/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	if spans[0].Name() != "GET /user/{id}" {
+		t.Errorf("span name = %q, want the route pattern", spans[0].Name())
+	}
+	if spans[0].SpanContext().TraceID().String() != traceID {
+		t.Errorf("span did not continue the incoming trace")
+	}
+}
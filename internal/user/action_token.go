@@ -0,0 +1,55 @@
+package user
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenPurpose string
+
+const (
+	PurposeVerifyEmail   TokenPurpose = "verify_email"
+	PurposeResetPassword TokenPurpose = "reset_password"
+)
+
+const (
+	verificationTokenTTL  = 24 * time.Hour
+	passwordResetTokenTTL = time.Hour
+)
+
+// ActionToken is a single-use, expiring token mailed to a user to prove they
+// control their address. Like refresh tokens only the hash is kept.
+type ActionToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   TokenPurpose
+	hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func newActionToken(userID uuid.UUID, purpose TokenPurpose, ttl time.Duration) (*ActionToken, string, error) {
+	value, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	return &ActionToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		hash:      hashToken(value),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, value, nil
+}
+
+func (t *ActionToken) Used() bool {
+	return t.UsedAt != nil
+}
+
+func (t *ActionToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ActionTokenStore interface {
+	Add(context.Context, *ActionToken) error
+	GetByHash(context.Context, TokenPurpose, []byte) (*ActionToken, error)
+	// Use marks the token as used. It reports false if it already was.
+	Use(context.Context, uuid.UUID) (bool, error)
+	// InvalidateForUser marks every unused token of the user with the given
+	// purpose as used, so only the latest mailed token works.
+	InvalidateForUser(context.Context, uuid.UUID, TokenPurpose) error
+}
+
+type InMemActionTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*ActionToken
+}
+
+func NewInMemActionTokenStore() *InMemActionTokenStore {
+	return &InMemActionTokenStore{
+		tokens: make(map[string]*ActionToken),
+	}
+}
+
+func (s *InMemActionTokenStore) Add(_ context.Context, t *ActionToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[string(t.hash)] = t
+	return nil
+}
+
+func (s *InMemActionTokenStore) GetByHash(_ context.Context, purpose TokenPurpose, hash []byte) (*ActionToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[string(hash)]
+	if !ok || t.Purpose != purpose {
+		return nil, ErrTokenNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+func (s *InMemActionTokenStore) Use(_ context.Context, id uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.ID != id {
+			continue
+		}
+		if t.UsedAt != nil {
+			return false, nil
+		}
+		now := time.Now()
+		t.UsedAt = &now
+		return true, nil
+	}
+	return false, ErrTokenNotFound
+}
+
+func (s *InMemActionTokenStore) InvalidateForUser(_ context.Context, userID uuid.UUID, purpose TokenPurpose) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &now
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package user
+
+import "errors"
+
+// Domain errors returned by Store implementations and the Service. Compare
+// with errors.Is; they may be wrapped.
+var (
+	ErrNotFound        = errors.New("user not found")
+	ErrDuplicateEmail  = errors.New("email already taken")
+	ErrDuplicateName   = errors.New("name already taken")
+	ErrInvalidPassword = errors.New("invalid password")
+	ErrInvalidEmail    = errors.New("invalid email")
+	// ErrInvalidCredentials is returned by Authenticate for an unknown user
+	// and for a wrong password alike, so callers can't probe for accounts.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrNotActivated       = errors.New("user is not activated")
+	// ErrDisabled is returned for a user an operator has disabled, whether
+	// or not they are activated.
+	ErrDisabled = errors.New("user is disabled")
+	// ErrTokenNotFound is returned by RefreshStore and ActionTokenStore for
+	// a token they don't hold.
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrInvalidToken is returned by the Service for a token that is
+	// unknown, malformed, expired, used or revoked.
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// StoreError reports that a store failed to run a query, for example
+// because the database is unreachable or the query timed out. It is never
+// used for a query that simply found nothing.
+type StoreError struct {
+	// Op describes what was attempted, such as "add user".
+	Op  string
+	Err error
+}
+
+func (e *StoreError) Error() string {
+	return "failed to " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *StoreError) Unwrap() error {
+	return e.Err
+}
+
+func storeError(op string, err error) error {
+	return &StoreError{Op: op, Err: err}
+}
@@ -2,15 +2,24 @@ package user
 
 import (
 	"awesomeProject/internal/apperror"
+	"awesomeProject/pkg/authmw"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
+// AdminRole lets a caller manage accounts other than their own.
+const AdminRole = "admin"
+
 type Handler struct {
 	Service Service
 	Logger  *slog.Logger
@@ -19,13 +28,13 @@ type Handler struct {
 func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) error {
 	nu := &CreationDTO{}
 	err := json.NewDecoder(r.Body).Decode(nu)
-	h.Logger.Info("Creating user", "newuser", nu)
 	if err != nil {
-		return err
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
 	}
-	user, err := h.Service.CreateNewUser(nu.Name, nu.Email, nu.Password)
+	h.Logger.InfoContext(r.Context(), "Creating user", "name", nu.Name, "email", nu.Email)
+	user, err := h.Service.CreateNewUser(r.Context(), nu.Name, nu.Email, nu.Password)
 	if err != nil {
-		return apperror.BadRequest(err)
+		return serviceError(err, apperror.BadRequest)
 	}
 	dto := DTO{
 		ID:        user.ID,
@@ -46,11 +55,101 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) error {
 	id := chi.URLParam(r, "id")
 	parsedId, err := uuid.Parse(id)
 	if err != nil {
-		return apperror.BadRequest(err)
+		return apperror.BadRequest(err).WithCode(apperror.CodeInvalidUserID)
+	}
+	u, err := h.Service.GetUserByID(r.Context(), parsedId)
+	if err != nil {
+		return serviceError(err, nil)
+	}
+	dto := DTO{
+		ID:        u.ID,
+		Name:      u.Name,
+		Email:     u.Email,
+		Activated: u.Activated,
+		Joined:    u.Joined,
+	}
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(dto)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// Me returns the user identified by the bearer token together with the roles
+// the token carries. It must be mounted behind authmw.Middleware.
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) error {
+	parsedId, err := uuid.Parse(authmw.Subject(r.Context()))
+	if err != nil {
+		return apperror.Unauthorized(errors.New("invalid token subject")).WithCode(apperror.CodeAuthInvalidToken)
+	}
+	u, err := h.Service.GetUserByID(r.Context(), parsedId)
+	if err != nil {
+		return serviceError(err, nil)
+	}
+	roles := authmw.Roles(r.Context())
+	if roles == nil {
+		roles = []string{}
+	}
+	dto := MeDTO{
+		DTO: DTO{
+			ID:        u.ID,
+			Name:      u.Name,
+			Email:     u.Email,
+			Activated: u.Activated,
+			Joined:    u.Joined,
+		},
+		Roles: roles,
 	}
-	u, err := h.Service.GetUserByID(parsedId)
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(dto)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) error {
+	parsedId, err := uuid.Parse(authmw.Subject(r.Context()))
+	if err != nil {
+		return apperror.Unauthorized(errors.New("invalid token subject")).WithCode(apperror.CodeAuthInvalidToken)
+	}
+	pw := &PasswordChangeWrapper{}
+	err = json.NewDecoder(r.Body).Decode(pw)
+	if err != nil {
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+	}
+	violations := requiredViolations("current_password", pw.CurrentPassword)
+	if pw.NewPassword == "" {
+		violations = append(violations, requiredViolations("new_password", pw.NewPassword)...)
+	} else {
+		// Checked here too so violations name the field the client sent.
+		violations = append(violations, passwordViolations("new_password", pw.NewPassword)...)
+	}
+	if err := validationError(violations); err != nil {
+		return serviceError(err, nil)
+	}
+	err = h.Service.ChangePassword(r.Context(), parsedId, pw.CurrentPassword, pw.NewPassword)
 	if err != nil {
-		return apperror.NotFound(err)
+		return serviceError(err, apperror.BadRequest)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) error {
+	actorID, targetID, err := authorizeSelfOrAdmin(r)
+	if err != nil {
+		return err
+	}
+	update := UpdateDTO{}
+	err = json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+	}
+	u, err := h.Service.UpdateUser(r.Context(), actorID, targetID, update)
+	if err != nil {
+		return serviceError(err, apperror.BadRequest)
 	}
 	dto := DTO{
 		ID:        u.ID,
@@ -67,28 +166,158 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) error {
+	actorID, targetID, err := authorizeSelfOrAdmin(r)
+	if err != nil {
+		return err
+	}
+	err = h.Service.DeleteUser(r.Context(), actorID, targetID)
+	if err != nil {
+		return serviceError(err, nil)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// RestoreUser undoes a recent account deletion. Routes must restrict it to
+// AdminRole.
+func (h *Handler) RestoreUser(w http.ResponseWriter, r *http.Request) error {
+	actorID, err := uuid.Parse(authmw.Subject(r.Context()))
+	if err != nil {
+		return apperror.Unauthorized(errors.New("invalid token subject")).WithCode(apperror.CodeAuthInvalidToken)
+	}
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return apperror.BadRequest(err).WithCode(apperror.CodeInvalidUserID)
+	}
+	err = h.Service.RestoreUser(r.Context(), actorID, targetID)
+	if err != nil {
+		return serviceError(err, nil)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// serviceError translates an error returned by Service. Domain errors get
+// their own status and store failures a 503 without the database details;
+// anything else goes through fallback, or is returned as is (and written as a
+// 500) when fallback is nil.
+func serviceError(err error, fallback func(error) *apperror.HTTPError) error {
+	var validationErr *ValidationError
+	var storeErr *StoreError
+	switch {
+	case errors.As(err, &validationErr):
+		return validationProblem(validationErr)
+	case errors.Is(err, ErrNotFound):
+		return apperror.NotFound(err).WithCode(apperror.CodeUserNotFound)
+	case errors.Is(err, ErrDuplicateName):
+		return apperror.Conflict(err).WithCode(apperror.CodeUserNameTaken)
+	case errors.Is(err, ErrDuplicateEmail):
+		return apperror.Conflict(err).WithCode(apperror.CodeUserEmailTaken)
+	case errors.Is(err, ErrInvalidPassword), errors.Is(err, ErrInvalidEmail):
+		return apperror.BadRequest(err)
+	case errors.Is(err, ErrInvalidCredentials):
+		return apperror.Unauthorized(err).WithCode(apperror.CodeAuthInvalidCredentials)
+	case errors.Is(err, ErrNotActivated):
+		return apperror.Unauthorized(err).WithCode(apperror.CodeAuthUserInactive)
+	case errors.Is(err, ErrDisabled):
+		return apperror.Forbidden(err).WithCode(apperror.CodeAuthUserDisabled)
+	case errors.Is(err, ErrWrongPassword):
+		return apperror.Forbidden(err).WithCode(apperror.CodeAuthWrongPassword)
+	case errors.As(err, &storeErr):
+		return apperror.NewHTTPErrorWithMessage(err, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)).WithCode(apperror.CodeServiceUnavailable)
+	}
+	if fallback == nil {
+		return err
+	}
+	return fallback(err)
+}
+
+// tokenError answers invalid for a token the service rejected and maps
+// anything else with serviceError, so a failing store isn't reported as a
+// bad token.
+func tokenError(err error, invalid *apperror.HTTPError) error {
+	if errors.Is(err, ErrInvalidToken) {
+		return invalid
+	}
+	return serviceError(err, nil)
+}
+
+// oauthError translates a Service error for the OAuth endpoints. Their
+// clients only get the OAuth error code, never the cause.
+func oauthError(err error) error {
+	var storeErr *StoreError
+	if errors.As(err, &storeErr) {
+		return &apperror.OAuthError{Err: err, StatusCode: http.StatusServiceUnavailable, Code: apperror.OAuthTemporarilyUnavailable}
+	}
+	return &apperror.OAuthError{Err: err, StatusCode: http.StatusInternalServerError, Code: apperror.OAuthServerError}
+}
+
+func validationProblem(err *ValidationError) *apperror.HTTPError {
+	fields := make([]apperror.FieldError, len(err.Violations))
+	for i, v := range err.Violations {
+		fields[i] = apperror.FieldError{Field: v.Field, Code: v.Code, Detail: v.Message, Params: v.Params}
+	}
+	return apperror.Validation(err, fields...)
+}
+
+// authorizeSelfOrAdmin returns the caller's ID and the {id} URL parameter,
+// failing with 403 unless they match or the caller holds AdminRole.
+func authorizeSelfOrAdmin(r *http.Request) (uuid.UUID, uuid.UUID, error) {
+	actorID, err := uuid.Parse(authmw.Subject(r.Context()))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apperror.Unauthorized(errors.New("invalid token subject")).WithCode(apperror.CodeAuthInvalidToken)
+	}
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, apperror.BadRequest(err).WithCode(apperror.CodeInvalidUserID)
+	}
+	if actorID != targetID && !authmw.HasRole(r.Context(), AdminRole) {
+		return uuid.Nil, uuid.Nil, apperror.Forbidden(errors.New("not allowed to modify this user")).WithCode(apperror.CodeUserModifyForbidden)
+	}
+	return actorID, targetID, nil
+}
+
 func (h *Handler) SearchUser(w http.ResponseWriter, r *http.Request) error {
 	name := r.URL.Query().Get("name")
 	email := r.URL.Query().Get("email")
 	if (name == "") && (email == "") {
-		return apperror.NewHTTPError(errors.New("name or email must be provided"), http.StatusBadRequest)
+		return apperror.BadRequest(errors.New("name or email must be provided")).WithCode(apperror.CodeSearchCriteriaRequired)
 	}
-	users := make([]User, 0)
+	users := make([]*User, 0)
 	if name != "" {
-		if u, err := h.Service.GetUserByName(name); err == nil {
-			users = append(users, *u)
+		u, err := h.Service.GetUserByName(r.Context(), name)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return serviceError(err, nil)
+		}
+		if err == nil {
+			users = append(users, u)
 		}
 	}
 	if email != "" {
-		if u, err := h.Service.GetUserByEmail(email); err == nil {
-			users = append(users, *u)
+		u, err := h.Service.GetUserByEmail(r.Context(), email)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return serviceError(err, nil)
+		}
+		if err == nil {
+			users = append(users, u)
 		}
 	}
 	if len(users) == 0 {
-		return apperror.NotFound(errors.New("user not found"))
+		return apperror.NotFound(ErrNotFound).WithCode(apperror.CodeUserNotFound)
+	}
+	dto := make([]DTO, 0, len(users))
+	for _, u := range users {
+		dto = append(dto, DTO{
+			ID:        u.ID,
+			Name:      u.Name,
+			Email:     u.Email,
+			Activated: u.Activated,
+			Joined:    u.Joined,
+		})
 	}
 	w.WriteHeader(http.StatusOK)
-	err := json.NewEncoder(w).Encode(users)
+	err := json.NewEncoder(w).Encode(dto)
 	if err != nil {
 		return err
 	}
@@ -99,17 +328,36 @@ func (h *Handler) Authenticate(w http.ResponseWriter, r *http.Request) error {
 	pw := &PasswordWrapper{}
 	err := json.NewDecoder(r.Body).Decode(pw)
 	if err != nil {
-		return err
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
 	}
-	if pw.Identifier == "" || pw.Password == "" {
-		return apperror.NewHTTPError(errors.New("identifier and password must be provided"), http.StatusBadRequest)
+	violations := append(requiredViolations("identifier", pw.Identifier), requiredViolations("password", pw.Password)...)
+	if err := validationError(violations); err != nil {
+		return serviceError(err, nil)
 	}
-	token, err := h.Service.Authenticate(pw.Identifier, pw.Password)
+	tw, err := h.Service.Authenticate(r.Context(), pw.Identifier, pw.Password)
 	if err != nil {
-		return apperror.Unauthorized(err)
+		return serviceError(err, apperror.Unauthorized)
 	}
-	tw := TokenWrapper{
-		Token: token,
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(tw)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) error {
+	rw := &RefreshWrapper{}
+	err := json.NewDecoder(r.Body).Decode(rw)
+	if err != nil {
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+	}
+	if err := validationError(requiredViolations("refresh_token", rw.RefreshToken)); err != nil {
+		return serviceError(err, nil)
+	}
+	tw, err := h.Service.Refresh(r.Context(), rw.RefreshToken)
+	if err != nil {
+		return tokenError(err, apperror.Unauthorized(err).WithCode(apperror.CodeRefreshTokenInvalid))
 	}
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(tw)
@@ -119,6 +367,284 @@ func (h *Handler) Authenticate(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+func (h *Handler) VerifyUser(w http.ResponseWriter, r *http.Request) error {
+	vw := &VerificationWrapper{}
+	err := json.NewDecoder(r.Body).Decode(vw)
+	if err != nil {
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+	}
+	if err := validationError(requiredViolations("token", vw.Token)); err != nil {
+		return serviceError(err, nil)
+	}
+	err = h.Service.VerifyEmail(r.Context(), vw.Token)
+	if err != nil {
+		return tokenError(err, apperror.BadRequest(err).WithCode(apperror.CodeVerifyTokenInvalid))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// ResendVerification always answers 202 so it doesn't reveal which addresses
+// have accounts. Failures are only logged, like in ForgotPassword.
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) error {
+	ew := &EmailWrapper{}
+	err := json.NewDecoder(r.Body).Decode(ew)
+	if err != nil {
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+	}
+	if err := validationError(requiredViolations("email", ew.Email)); err != nil {
+		return serviceError(err, nil)
+	}
+	err = h.Service.ResendVerification(r.Context(), ew.Email)
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "Failed to resend verification mail", "error", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// ForgotPassword always answers 202 so it doesn't reveal which addresses
+// have accounts. A failure to look up the user or send the mail is only
+// logged, since only existing accounts get that far.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) error {
+	ew := &EmailWrapper{}
+	err := json.NewDecoder(r.Body).Decode(ew)
+	if err != nil {
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+	}
+	if err := validationError(requiredViolations("email", ew.Email)); err != nil {
+		return serviceError(err, nil)
+	}
+	err = h.Service.ForgotPassword(r.Context(), ew.Email)
+	if err != nil {
+		h.Logger.ErrorContext(r.Context(), "Failed to send password reset mail", "error", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	rw := &PasswordResetWrapper{}
+	err := json.NewDecoder(r.Body).Decode(rw)
+	if err != nil {
+		return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+	}
+	violations := append(requiredViolations("token", rw.Token), requiredViolations("password", rw.Password)...)
+	if err := validationError(violations); err != nil {
+		return serviceError(err, nil)
+	}
+	err = h.Service.ResetPassword(r.Context(), rw.Token, rw.Password)
+	if err != nil {
+		return tokenError(err, apperror.BadRequest(err).WithCode(apperror.CodeResetTokenInvalid))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) error {
+	token, ok := bearerToken(r)
+	if !ok {
+		return apperror.Unauthorized(errors.New("bearer token must be provided")).WithCode(apperror.CodeAuthMissingBearer)
+	}
+	rw := &RefreshWrapper{}
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(rw)
+		if err != nil {
+			return apperror.BadRequest(err).WithCode(apperror.CodeMalformedBody)
+		}
+	}
+	err := h.Service.Logout(r.Context(), token, rw.RefreshToken)
+	if err != nil {
+		return tokenError(err, apperror.Unauthorized(err).WithCode(apperror.CodeAuthInvalidToken))
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// RevokeToken implements the RFC 7009 revocation endpoint. The request is
+// form encoded and the response is 200 even for unknown tokens. Errors are
+// OAuth error responses.
+func (h *Handler) RevokeToken(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return apperror.InvalidRequest(err)
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		return apperror.InvalidRequest(errors.New("token must be provided"))
+	}
+	err = h.Service.RevokeToken(r.Context(), token, r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		return oauthError(err)
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// Introspect implements the RFC 7662 introspection endpoint. Callers are
+// authenticated with client credentials before reaching it. Errors are OAuth
+// error responses.
+func (h *Handler) Introspect(w http.ResponseWriter, r *http.Request) error {
+	err := r.ParseForm()
+	if err != nil {
+		return apperror.InvalidRequest(err)
+	}
+	token := r.PostForm.Get("token")
+	if token == "" {
+		return apperror.InvalidRequest(errors.New("token must be provided"))
+	}
+	result, err := h.Service.Introspect(r.Context(), token, r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		return oauthError(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(result)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// ListUsers serves GET /users. Routes must restrict it to AdminRole.
+//
+// Query parameters: limit, cursor (next_cursor of the previous page),
+// order (asc|desc by join date), activated (true|false), joined_after and
+// joined_before (RFC 3339), name_prefix, email_prefix and total=true to
+// include the number of matching users.
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) error {
+	q, err := parseListQuery(r.URL.Query())
+	if errors.Is(err, errInvalidLimit) {
+		return apperror.BadRequest(err).WithCode(apperror.CodeInvalidLimit)
+	}
+	if err != nil {
+		return apperror.BadRequest(err)
+	}
+	page, err := h.Service.ListUsers(r.Context(), q)
+	if err != nil {
+		return serviceError(err, apperror.BadRequest)
+	}
+	dto := ListDTO{
+		Users: make([]DTO, 0, len(page.Users)),
+		Total: page.Total,
+	}
+	for _, u := range page.Users {
+		dto.Users = append(dto.Users, DTO{
+			ID:        u.ID,
+			Name:      u.Name,
+			Email:     u.Email,
+			Activated: u.Activated,
+			Joined:    u.Joined,
+		})
+	}
+	if page.Next != nil {
+		dto.NextCursor = page.Next.Encode()
+	}
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(dto)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// FuzzySearchUsers serves GET /users/search?q=&limit=. Routes must restrict
+// it to AdminRole.
+func (h *Handler) FuzzySearchUsers(w http.ResponseWriter, r *http.Request) error {
+	limit := 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		var err error
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			return apperror.BadRequest(fmt.Errorf("%w %q", errInvalidLimit, s)).WithCode(apperror.CodeInvalidLimit)
+		}
+	}
+	results, err := h.Service.FuzzySearchUsers(r.Context(), r.URL.Query().Get("q"), limit)
+	if err != nil {
+		return serviceError(err, apperror.BadRequest)
+	}
+	dto := SearchResultsDTO{Results: make([]SearchResultDTO, 0, len(results))}
+	for _, res := range results {
+		dto.Results = append(dto.Results, SearchResultDTO{
+			User: DTO{
+				ID:        res.User.ID,
+				Name:      res.User.Name,
+				Email:     res.User.Email,
+				Activated: res.User.Activated,
+				Joined:    res.User.Joined,
+			},
+			Score: res.Score,
+		})
+	}
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(dto)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// errInvalidLimit is returned for a limit that isn't a positive integer.
+var errInvalidLimit = errors.New("invalid limit")
+
+func parseListQuery(v url.Values) (ListQuery, error) {
+	q := ListQuery{
+		Order: SortOrder(v.Get("order")),
+		Filter: ListFilter{
+			NamePrefix:  v.Get("name_prefix"),
+			EmailPrefix: v.Get("email_prefix"),
+		},
+	}
+	var err error
+	if s := v.Get("limit"); s != "" {
+		q.Limit, err = strconv.Atoi(s)
+		if err != nil || q.Limit < 1 {
+			return q, fmt.Errorf("%w %q", errInvalidLimit, s)
+		}
+	}
+	if s := v.Get("cursor"); s != "" {
+		q.After, err = DecodeCursor(s)
+		if err != nil {
+			return q, err
+		}
+	}
+	if s := v.Get("activated"); s != "" {
+		activated, err := strconv.ParseBool(s)
+		if err != nil {
+			return q, fmt.Errorf("invalid activated %q", s)
+		}
+		q.Filter.Activated = &activated
+	}
+	for param, dst := range map[string]**time.Time{
+		"joined_after":  &q.Filter.JoinedAfter,
+		"joined_before": &q.Filter.JoinedBefore,
+	} {
+		if s := v.Get(param); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s %q", param, s)
+			}
+			t = t.UTC()
+			*dst = &t
+		}
+	}
+	if s := v.Get("total"); s != "" {
+		q.WithTotal, err = strconv.ParseBool(s)
+		if err != nil {
+			return q, fmt.Errorf("invalid total %q", s)
+		}
+	}
+	return q, nil
+}
+
 type SearchDTO struct {
 	Name  string
 	Email string
@@ -2,24 +2,32 @@ package user
 
 import (
 	"awesomeProject/internal/apperror"
+	"awesomeProject/internal/mail"
+	"awesomeProject/pkg/authmw"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func TestHandler_Authenticate(t *testing.T) {
-	t.Setenv("SIGN_KEY", "secret")
 
 	service := createTestService(t, "valid", "password", "valid@email.test")
-	h := &Handler{Service: service}
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
 
 	tests := []struct {
 		name      string
@@ -28,29 +36,1030 @@ func TestHandler_Authenticate(t *testing.T) {
 	}{
 		{
 			name:      "valid user",
-			body:      PasswordWrapper{Password: "password", Email: "valid@email.test"},
+			body:      PasswordWrapper{Password: "password", Identifier: "valid@email.test"},
 			wantError: nil,
 		},
 		{
 			name:      "wrong password",
-			body:      PasswordWrapper{Password: "wrong", Email: "valid@email.test"},
+			body:      PasswordWrapper{Password: "wrong", Identifier: "valid@email.test"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized, Code: apperror.CodeAuthInvalidCredentials},
+		},
+		{
+			name:      "no user found",
+			body:      PasswordWrapper{Password: "password", Identifier: "invalid@email.test"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized, Code: apperror.CodeAuthInvalidCredentials},
+		},
+		{
+			name:      "empty password",
+			body:      PasswordWrapper{Password: "", Identifier: "valid@email.test"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest, Code: apperror.CodeValidationFailed},
+		},
+		{
+			name:      "empty email",
+			body:      PasswordWrapper{Password: "password", Identifier: ""},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest, Code: apperror.CodeValidationFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			err = h.Authenticate(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+				assert.Equal(t, tt.wantError.Code, httpError.Code)
+			}
+		})
+	}
+
+	t.Run("malformed body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/authenticate", strings.NewReader("{"))
+		err := h.Authenticate(httptest.NewRecorder(), req)
+		var httpError *apperror.HTTPError
+		if assert.ErrorAs(t, err, &httpError) {
+			assert.Equal(t, http.StatusBadRequest, httpError.StatusCode)
+			assert.Equal(t, apperror.CodeMalformedBody, httpError.Code)
+		}
+	})
+}
+
+func TestHandler_RefreshToken(t *testing.T) {
+
+	service := createTestService(t, "valid", "password", "valid@email.test")
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tw, err := service.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		body      RefreshWrapper
+		wantError *apperror.HTTPError
+	}{
+		{
+			name:      "valid refresh token",
+			body:      RefreshWrapper{RefreshToken: tw.RefreshToken},
+			wantError: nil,
+		},
+		{
+			name:      "reused refresh token",
+			body:      RefreshWrapper{RefreshToken: tw.RefreshToken},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized, Code: apperror.CodeRefreshTokenInvalid},
+		},
+		{
+			name:      "unknown refresh token",
+			body:      RefreshWrapper{RefreshToken: "unknown"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized, Code: apperror.CodeRefreshTokenInvalid},
+		},
+		{
+			name:      "empty refresh token",
+			body:      RefreshWrapper{RefreshToken: ""},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest, Code: apperror.CodeValidationFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/token/refresh", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			err = h.RefreshToken(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				var got TokenWrapper
+				err = json.NewDecoder(w.Body).Decode(&got)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.NotEmpty(t, got.Token)
+				assert.NotEmpty(t, got.RefreshToken)
+				assert.NotEqual(t, tt.body.RefreshToken, got.RefreshToken)
+			} else {
+				assert.Error(t, err)
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+				assert.Equal(t, tt.wantError.Code, httpError.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_Logout(t *testing.T) {
+
+	service := createTestService(t, "valid", "password", "valid@email.test")
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tw, err := service.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		body      string
+		wantError *apperror.HTTPError
+	}{
+		{
+			name:      "missing bearer token",
+			header:    "",
+			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized, Code: apperror.CodeAuthMissingBearer},
+		},
+		{
+			name:      "invalid bearer token",
+			header:    "Bearer invalid",
+			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized},
+		},
+		{
+			name:      "valid token",
+			header:    "Bearer " + tw.Token,
+			body:      `{"refresh_token": "` + tw.RefreshToken + `"}`,
+			wantError: nil,
+		},
+		{
+			name:      "already logged out",
+			header:    "Bearer " + tw.Token,
 			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized},
 		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/logout", bytes.NewReader([]byte(tt.body)))
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			err := h.Logout(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusNoContent, w.Code)
+			} else {
+				assert.Error(t, err)
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+				if tt.wantError.Code != "" {
+					assert.Equal(t, tt.wantError.Code, httpError.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_RevokeToken(t *testing.T) {
+
+	service := createTestService(t, "valid", "password", "valid@email.test")
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tw, err := service.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		form      url.Values
+		wantError *apperror.OAuthError
+	}{
+		{
+			name:      "refresh token",
+			form:      url.Values{"token": {tw.RefreshToken}, "token_type_hint": {"refresh_token"}},
+			wantError: nil,
+		},
+		{
+			name:      "access token",
+			form:      url.Values{"token": {tw.Token}},
+			wantError: nil,
+		},
+		{
+			name:      "unknown token",
+			form:      url.Values{"token": {"unknown"}},
+			wantError: nil,
+		},
+		{
+			name:      "missing token",
+			form:      url.Values{},
+			wantError: &apperror.OAuthError{StatusCode: http.StatusBadRequest, Code: apperror.OAuthInvalidRequest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/token/revoke", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			err := h.RevokeToken(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusOK, w.Code)
+			} else {
+				var oauthError *apperror.OAuthError
+				if assert.ErrorAs(t, err, &oauthError) {
+					assert.Equal(t, tt.wantError.StatusCode, oauthError.StatusCode)
+					assert.Equal(t, tt.wantError.Code, oauthError.Code)
+				}
+			}
+		})
+	}
+
+	if _, err := service.Refresh(t.Context(), tw.RefreshToken); err == nil {
+		t.Errorf("refresh token is still valid after revocation")
+	}
+}
+
+func TestHandler_Introspect(t *testing.T) {
+
+	service := createTestService(t, "valid", "password", "valid@email.test")
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tw, err := service.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		wantActive bool
+		wantError  *apperror.OAuthError
+	}{
+		{
+			name:       "access token of an unactivated user",
+			form:       url.Values{"token": {tw.Token}},
+			wantActive: false,
+		},
+		{
+			name:       "unknown token",
+			form:       url.Values{"token": {"unknown"}},
+			wantActive: false,
+		},
+		{
+			name:      "missing token",
+			form:      url.Values{},
+			wantError: &apperror.OAuthError{StatusCode: http.StatusBadRequest, Code: apperror.OAuthInvalidRequest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+
+			err := h.Introspect(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				var got Introspection
+				err = json.NewDecoder(w.Body).Decode(&got)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, tt.wantActive, got.Active)
+			} else {
+				var oauthError *apperror.OAuthError
+				if assert.ErrorAs(t, err, &oauthError) {
+					assert.Equal(t, tt.wantError.StatusCode, oauthError.StatusCode)
+					assert.Equal(t, tt.wantError.Code, oauthError.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_VerifyUser(t *testing.T) {
+	service, box := newVerificationTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	if _, err := service.CreateNewUser(t.Context(), "valid", "valid@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+	token := box.lastToken(t)
+
+	tests := []struct {
+		name      string
+		body      VerificationWrapper
+		wantError *apperror.HTTPError
+	}{
+		{
+			name:      "valid token",
+			body:      VerificationWrapper{Token: token},
+			wantError: nil,
+		},
+		{
+			name:      "token already used",
+			body:      VerificationWrapper{Token: token},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+		{
+			name:      "unknown token",
+			body:      VerificationWrapper{Token: "unknown"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+		{
+			name:      "empty token",
+			body:      VerificationWrapper{Token: ""},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/user/verify", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			err = h.VerifyUser(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusNoContent, w.Code)
+			} else {
+				assert.Error(t, err)
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandler_ResendVerification(t *testing.T) {
+	service, _ := newVerificationTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	if _, err := service.CreateNewUser(t.Context(), "valid", "valid@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		body      EmailWrapper
+		wantError *apperror.HTTPError
+	}{
+		{name: "known email", body: EmailWrapper{Email: "valid@email.test"}},
+		{name: "unknown email", body: EmailWrapper{Email: "unknown@email.test"}},
+		{name: "empty email", body: EmailWrapper{}, wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/user/verify/resend", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			err = h.ResendVerification(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusAccepted, w.Code)
+			} else {
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandler_ForgotPassword(t *testing.T) {
+	service, _, _ := newPasswordResetTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tests := []struct {
+		name      string
+		body      EmailWrapper
+		wantError *apperror.HTTPError
+	}{
+		{name: "known email", body: EmailWrapper{Email: "valid@email.test"}},
+		{name: "unknown email", body: EmailWrapper{Email: "unknown@email.test"}},
+		{name: "empty email", body: EmailWrapper{}, wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/password/forgot", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			err = h.ForgotPassword(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusAccepted, w.Code)
+			} else {
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+			}
+		})
+	}
+}
+
+// blockingMailer holds every send until release is closed, like a stalled
+// SMTP relay.
+type blockingMailer struct {
+	release chan struct{}
+	sent    chan mail.Message
+}
+
+func (m blockingMailer) Send(ctx context.Context, msg mail.Message) error {
+	select {
+	case <-m.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	m.sent <- msg
+	return nil
+}
+
+func TestHandler_ForgotPassword_DoesNotWaitForMail(t *testing.T) {
+	mailer := blockingMailer{release: make(chan struct{}), sent: make(chan mail.Message, 1)}
+	service, _ := newVerificationTestService(t, WithMailer(mailer, "http://localhost/verify?token="))
+	if _, err := service.CreateActivatedUser(t.Context(), uuid.Nil, "valid", "valid@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	req := httptest.NewRequest(http.MethodPost, "/password/forgot", strings.NewReader(`{"email":"valid@email.test"}`))
+	w := httptest.NewRecorder()
+
+	done := make(chan error, 1)
+	go func() { done <- h.ForgotPassword(w, req) }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ForgotPassword() waited for the mailer")
+	}
+
+	close(mailer.release)
+	service.WaitForMails()
+	select {
+	case m := <-mailer.sent:
+		assert.Equal(t, "valid@email.test", m.To)
+	default:
+		t.Error("ForgotPassword() did not send the mail in the background")
+	}
+}
+
+func TestHandler_ResendVerification_DoesNotWaitForMail(t *testing.T) {
+	mailer := blockingMailer{release: make(chan struct{}), sent: make(chan mail.Message, 2)}
+	service, _ := newVerificationTestService(t, WithMailer(mailer, "http://localhost/verify?token="))
+	if _, err := service.CreateNewUser(t.Context(), "valid", "valid@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	req := httptest.NewRequest(http.MethodPost, "/user/verify/resend", strings.NewReader(`{"email":"valid@email.test"}`))
+	w := httptest.NewRecorder()
+
+	done := make(chan error, 1)
+	go func() { done <- h.ResendVerification(w, req) }()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ResendVerification() waited for the mailer")
+	}
+
+	close(mailer.release)
+	service.WaitForMails()
+	assert.Len(t, mailer.sent, 2, "ResendVerification() did not send the mail in the background")
+}
+
+// failingMailer fails every send the way an unreachable SMTP server does.
+type failingMailer struct{}
+
+func (failingMailer) Send(context.Context, mail.Message) error {
+	return errors.New("connection refused")
+}
+
+func TestHandler_ResendVerification_Failure(t *testing.T) {
+	failingMail, _ := newVerificationTestService(t, WithMailer(failingMailer{}, "http://localhost/verify?token="))
+	if _, err := failingMail.CreateNewUser(t.Context(), "valid", "valid@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		service Service
+	}{
+		{name: "mailer fails", service: failingMail},
+		{name: "store unavailable", service: newService(t, unavailableStore{NewInMemStore()})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{Service: tt.service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			req := httptest.NewRequest(http.MethodPost, "/user/verify/resend", strings.NewReader(`{"email":"valid@email.test"}`))
+			w := httptest.NewRecorder()
+
+			err := h.ResendVerification(w, req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusAccepted, w.Code)
+		})
+	}
+}
+
+func TestHandler_ForgotPassword_Failure(t *testing.T) {
+	failingMail, _ := newVerificationTestService(t, WithMailer(failingMailer{}, "http://localhost/verify?token="))
+	if _, err := failingMail.CreateNewUser(t.Context(), "valid", "valid@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		service Service
+	}{
+		{name: "mailer fails", service: failingMail},
+		{name: "store unavailable", service: newService(t, unavailableStore{NewInMemStore()})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &Handler{Service: tt.service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+			req := httptest.NewRequest(http.MethodPost, "/password/forgot", strings.NewReader(`{"email":"valid@email.test"}`))
+			w := httptest.NewRecorder()
+
+			err := h.ForgotPassword(w, req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusAccepted, w.Code)
+		})
+	}
+}
+
+func TestHandler_ResetPassword(t *testing.T) {
+	service, box, _ := newPasswordResetTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	if err := service.ForgotPassword(t.Context(), "valid@email.test"); err != nil {
+		t.Fatal(err)
+	}
+	token := box.lastToken(t)
+
+	tests := []struct {
+		name      string
+		body      PasswordResetWrapper
+		wantError *apperror.HTTPError
+	}{
+		{
+			name:      "password too short",
+			body:      PasswordResetWrapper{Token: token, Password: "short"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+		{
+			name:      "valid token",
+			body:      PasswordResetWrapper{Token: token, Password: "newpassword"},
+			wantError: nil,
+		},
+		{
+			name:      "token already used",
+			body:      PasswordResetWrapper{Token: token, Password: "newpassword"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+		{
+			name:      "missing password",
+			body:      PasswordResetWrapper{Token: token},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/password/reset", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			err = h.ResetPassword(w, req)
+
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusNoContent, w.Code)
+			} else {
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandler_CreateUser(t *testing.T) {
+	validName := "valid"
+	validEmail := "valid@email.test"
+	validPassword := "password"
+	existingName := "exists"
+	existingEmail := "existing@email.test"
+	invalidPassword := "pw"
+
+	service := createTestService(t, existingName, validPassword, existingEmail)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tests := []struct {
+		name      string
+		body      CreationDTO
+		wantError *apperror.HTTPError
+	}{
+		{
+			name: "valid user",
+			body: CreationDTO{
+				Name:     validName,
+				Email:    validEmail,
+				Password: validPassword,
+			},
+			wantError: nil,
+		},
+		{
+			name: "invalid password",
+			body: CreationDTO{
+				Name:     validName,
+				Email:    validEmail,
+				Password: invalidPassword,
+			},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+		{
+			name: "user already exists",
+			body: CreationDTO{
+				Name:     existingName,
+				Email:    existingEmail,
+				Password: validPassword,
+			},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusConflict},
+		},
+		{
+			name: "empty password",
+			body: CreationDTO{
+				Name:     validName,
+				Email:    validEmail,
+				Password: "",
+			},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+		{
+			name: "empty email",
+			body: CreationDTO{
+				Name:     validName,
+				Email:    "",
+				Password: validPassword,
+			},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/user", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			err = h.CreateUser(w, req)
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				var dto DTO
+				err = json.NewDecoder(w.Body).Decode(&dto)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.NotEmpty(t, dto.ID)
+				assert.Equal(t, dto.Name, tt.body.Name)
+				assert.Equal(t, dto.Email, tt.body.Email)
+			} else {
+				assert.Error(t, err)
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandler_CreateUser_Validation(t *testing.T) {
+	h := &Handler{Service: newService(t, NewInMemStore()), Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	body, err := json.Marshal(CreationDTO{Name: "valid", Email: "invalid", Password: "short"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/user", bytes.NewReader(body))
+
+	err = h.CreateUser(httptest.NewRecorder(), req)
+	var httpError *apperror.HTTPError
+	if assert.ErrorAs(t, err, &httpError) {
+		assert.Equal(t, http.StatusBadRequest, httpError.StatusCode)
+		assert.Equal(t, apperror.TypeValidation, httpError.Type)
+		assert.Equal(t, []apperror.FieldError{
+			{Field: "email", Code: apperror.CodeFieldInvalidEmail, Detail: "must be a valid email address"},
+			{Field: "password", Code: apperror.CodeFieldTooShort, Detail: "must be at least 8 characters", Params: map[string]string{"min": "8"}},
+		}, httpError.Errors)
+	}
+}
+
+func TestHandler_GetUser(t *testing.T) {
+	validID := uuid.New()
+	invalidID := uuid.New()
+	emptyID := ""
+	spaceID := "   "
+
+	service := createTestService(t, "valid", "password", "valid@email.test", validID)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tests := []struct {
+		name      string
+		id        string
+		wantError *apperror.HTTPError
+	}{
+		{
+			name:      "valid id",
+			id:        validID.String(),
+			wantError: nil,
+		},
+		{
+			name:      "invalid id",
+			id:        invalidID.String(),
+			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound},
+		},
+		{
+			name:      "empty id",
+			id:        emptyID,
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+		{
+			name:      "id with spaces",
+			id:        spaceID,
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/user/", nil)
+			w := httptest.NewRecorder()
+
+			chiCtx := &chi.Context{
+				URLParams: chi.RouteParams{
+					Keys:   []string{"id"},
+					Values: []string{tt.id},
+				},
+			}
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+
+			err := h.GetUser(w, req)
+			if tt.wantError == nil {
+				assert.NoError(t, err, "got error")
+				var dto DTO
+				err := json.NewDecoder(w.Body).Decode(&dto)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.NotEmpty(t, dto.ID)
+				assert.Equal(t, dto.ID, uuid.MustParse(tt.id))
+			} else {
+				assert.Error(t, err)
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+			}
+		})
+	}
+}
+
+// unavailableStore fails every lookup the way PostgresStore does when the
+// database can't be reached.
+type unavailableStore struct {
+	*InMemStore
+}
+
+func (unavailableStore) GetByID(context.Context, uuid.UUID) (*User, error) {
+	return nil, storeError("get user", errors.New("connection refused"))
+}
+
+func (unavailableStore) GetByEmail(context.Context, string) (*User, error) {
+	return nil, storeError("get user", errors.New("connection refused"))
+}
+
+func TestHandler_StoreUnavailable(t *testing.T) {
+	h := &Handler{
+		Service: newService(t, unavailableStore{NewInMemStore()}),
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/user/", nil)
+	chiCtx := &chi.Context{URLParams: chi.RouteParams{Keys: []string{"id"}, Values: []string{uuid.NewString()}}}
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+	err := h.GetUser(httptest.NewRecorder(), req)
+	var httpError *apperror.HTTPError
+	if assert.ErrorAs(t, err, &httpError) {
+		assert.Equal(t, http.StatusServiceUnavailable, httpError.StatusCode)
+		assert.NotContains(t, httpError.Error(), "connection refused")
+	}
+
+	body, _ := json.Marshal(PasswordWrapper{Identifier: "valid@email.test", Password: "password"})
+	req = httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	err = h.Authenticate(httptest.NewRecorder(), req)
+	if assert.ErrorAs(t, err, &httpError) {
+		assert.Equal(t, http.StatusServiceUnavailable, httpError.StatusCode)
+	}
+}
+
+// unavailableRefreshStore and unavailableActionTokenStore fail every lookup
+// the way the Postgres stores do when the database can't be reached.
+type unavailableRefreshStore struct {
+	*InMemRefreshStore
+}
+
+func (unavailableRefreshStore) GetByHash(context.Context, []byte) (*RefreshToken, error) {
+	return nil, storeError("get refresh token", errors.New("connection refused"))
+}
+
+type unavailableActionTokenStore struct {
+	*InMemActionTokenStore
+}
+
+func (unavailableActionTokenStore) GetByHash(context.Context, TokenPurpose, []byte) (*ActionToken, error) {
+	return nil, storeError("get token", errors.New("connection refused"))
+}
+
+func TestHandler_TokenStoreUnavailable(t *testing.T) {
+	h := &Handler{
+		Service: newService(t, NewInMemStore(),
+			WithRefreshStore(unavailableRefreshStore{NewInMemRefreshStore()}),
+			WithActionTokenStore(unavailableActionTokenStore{NewInMemActionTokenStore()}),
+		),
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	tests := []struct {
+		name    string
+		handler func(http.ResponseWriter, *http.Request) error
+		req     *http.Request
+	}{
+		{
+			name:    "refresh",
+			handler: h.RefreshToken,
+			req:     httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{"refresh_token":"token"}`)),
+		},
+		{
+			name:    "verify email",
+			handler: h.VerifyUser,
+			req:     httptest.NewRequest(http.MethodPost, "/user/verify", strings.NewReader(`{"token":"token"}`)),
+		},
+		{
+			name:    "reset password",
+			handler: h.ResetPassword,
+			req:     httptest.NewRequest(http.MethodPost, "/password/reset", strings.NewReader(`{"token":"token","password":"newPassword"}`)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.handler(httptest.NewRecorder(), tt.req)
+			var httpError *apperror.HTTPError
+			if assert.ErrorAs(t, err, &httpError) {
+				assert.Equal(t, http.StatusServiceUnavailable, httpError.StatusCode)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/introspect", strings.NewReader("token=token&token_type_hint=refresh_token"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	err := h.Introspect(httptest.NewRecorder(), req)
+	var oauthError *apperror.OAuthError
+	if assert.ErrorAs(t, err, &oauthError) {
+		assert.Equal(t, http.StatusServiceUnavailable, oauthError.StatusCode)
+		assert.Equal(t, apperror.OAuthTemporarilyUnavailable, oauthError.Code)
+	}
+}
+
+func TestHandler_Me(t *testing.T) {
+	validID := uuid.New()
+
+	service := createTestService(t, "valid", "password", "valid@email.test", validID)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tests := []struct {
+		name      string
+		claims    *authmw.Claims
+		wantRoles []string
+		wantError *apperror.HTTPError
+	}{
+		{
+			name:      "valid subject with roles",
+			claims:    &authmw.Claims{Roles: []string{"admin"}, RegisteredClaims: jwt.RegisteredClaims{Subject: validID.String()}},
+			wantRoles: []string{"admin"},
+		},
 		{
-			name:      "no user found",
-			body:      PasswordWrapper{Password: "password", Email: "invalid@email.test"},
+			name:      "valid subject without roles",
+			claims:    &authmw.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: validID.String()}},
+			wantRoles: []string{},
+		},
+		{
+			name:      "unknown subject",
+			claims:    &authmw.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: uuid.NewString()}},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound},
+		},
+		{
+			name:      "unauthenticated",
+			claims:    nil,
 			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized},
 		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			if tt.claims != nil {
+				req = req.WithContext(authmw.WithClaims(req.Context(), tt.claims))
+			}
+			w := httptest.NewRecorder()
+
+			err := h.Me(w, req)
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				var dto MeDTO
+				err := json.NewDecoder(w.Body).Decode(&dto)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, validID, dto.ID)
+				assert.Equal(t, "valid", dto.Name)
+				assert.Equal(t, tt.wantRoles, dto.Roles)
+			} else {
+				assert.Error(t, err)
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandler_ChangePassword(t *testing.T) {
+	validID := uuid.New()
+
+	service := createTestService(t, "valid", "password", "valid@email.test", validID)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	claims := &authmw.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: validID.String()}}
+
+	tests := []struct {
+		name      string
+		claims    *authmw.Claims
+		body      PasswordChangeWrapper
+		wantError *apperror.HTTPError
+	}{
+		{
+			name:      "wrong current password",
+			claims:    claims,
+			body:      PasswordChangeWrapper{CurrentPassword: "wrongpassword", NewPassword: "newpassword"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusForbidden},
+		},
 		{
-			name:      "empty password",
-			body:      PasswordWrapper{Password: "", Email: "valid@email.test"},
+			name:      "new password too short",
+			claims:    claims,
+			body:      PasswordChangeWrapper{CurrentPassword: "password", NewPassword: "short"},
 			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
 		},
 		{
-			name:      "empty email",
-			body:      PasswordWrapper{Password: "password", Email: ""},
+			name:      "missing new password",
+			claims:    claims,
+			body:      PasswordChangeWrapper{CurrentPassword: "password"},
 			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
 		},
+		{
+			name:      "unauthenticated",
+			claims:    nil,
+			body:      PasswordChangeWrapper{CurrentPassword: "password", NewPassword: "newpassword"},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized},
+		},
+		{
+			name:   "valid change",
+			claims: claims,
+			body:   PasswordChangeWrapper{CurrentPassword: "password", NewPassword: "newpassword"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -58,15 +1067,17 @@ func TestHandler_Authenticate(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			req := httptest.NewRequest(http.MethodPost, "/authenticate", bytes.NewReader(body))
+			req := httptest.NewRequest(http.MethodPut, "/me/password", bytes.NewReader(body))
+			if tt.claims != nil {
+				req = req.WithContext(authmw.WithClaims(req.Context(), tt.claims))
+			}
 			w := httptest.NewRecorder()
 
-			err = h.Authenticate(w, req)
-
+			err = h.ChangePassword(w, req)
 			if tt.wantError == nil {
 				assert.NoError(t, err)
+				assert.Equal(t, http.StatusNoContent, w.Code)
 			} else {
-				assert.Error(t, err)
 				var httpError *apperror.HTTPError
 				assert.ErrorAs(t, err, &httpError)
 				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
@@ -75,67 +1086,77 @@ func TestHandler_Authenticate(t *testing.T) {
 	}
 }
 
-func TestHandler_CreateUser(t *testing.T) {
-	t.Setenv("SIGN_KEY", "secret")
-	validName := "valid"
-	validEmail := "valid@email.test"
-	validPassword := "password"
-	existingName := "exists"
-	existingEmail := "existing@email.test"
-	invalidPassword := "pw"
-
-	service := createTestService(t, existingName, validPassword, existingEmail)
-	h := &Handler{Service: service}
+func TestHandler_UpdateUser(t *testing.T) {
+	service, _ := newVerificationTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	owner, err := service.CreateNewUser(t.Context(), "owner", "owner@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := service.CreateNewUser(t.Context(), "other", "other@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsFor := func(id uuid.UUID, roles ...string) *authmw.Claims {
+		return &authmw.Claims{Roles: roles, RegisteredClaims: jwt.RegisteredClaims{Subject: id.String()}}
+	}
+	str := func(s string) *string { return &s }
 
 	tests := []struct {
 		name      string
-		body      CreationDTO
+		claims    *authmw.Claims
+		id        string
+		body      UpdateDTO
+		wantName  string
 		wantError *apperror.HTTPError
 	}{
 		{
-			name: "valid user",
-			body: CreationDTO{
-				Name:     validName,
-				Email:    validEmail,
-				Password: validPassword,
-			},
-			wantError: nil,
+			name:     "owner renames themselves",
+			claims:   claimsFor(owner.ID),
+			id:       owner.ID.String(),
+			body:     UpdateDTO{Name: str("renamed")},
+			wantName: "renamed",
 		},
 		{
-			name: "invalid password",
-			body: CreationDTO{
-				Name:     validName,
-				Email:    validEmail,
-				Password: invalidPassword,
-			},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+			name:      "name taken",
+			claims:    claimsFor(owner.ID),
+			id:        owner.ID.String(),
+			body:      UpdateDTO{Name: str("other")},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusConflict},
 		},
 		{
-			name: "user already exists",
-			body: CreationDTO{
-				Name:     existingName,
-				Email:    existingEmail,
-				Password: validPassword,
-			},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+			name:      "email taken",
+			claims:    claimsFor(owner.ID),
+			id:        owner.ID.String(),
+			body:      UpdateDTO{Email: str("other@email.test")},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusConflict},
 		},
 		{
-			name: "empty password",
-			body: CreationDTO{
-				Name:     validName,
-				Email:    validEmail,
-				Password: "",
-			},
+			name:      "invalid email",
+			claims:    claimsFor(owner.ID),
+			id:        owner.ID.String(),
+			body:      UpdateDTO{Email: str("invalid")},
 			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
 		},
 		{
-			name: "empty email",
-			body: CreationDTO{
-				Name:     validName,
-				Email:    "",
-				Password: validPassword,
-			},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+			name:      "someone else",
+			claims:    claimsFor(other.ID),
+			id:        owner.ID.String(),
+			body:      UpdateDTO{Name: str("hijacked")},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusForbidden, Code: apperror.CodeUserModifyForbidden},
+		},
+		{
+			name:     "admin",
+			claims:   claimsFor(other.ID, AdminRole),
+			id:       owner.ID.String(),
+			body:     UpdateDTO{Name: str("moderated")},
+			wantName: "moderated",
+		},
+		{
+			name:      "unauthenticated",
+			id:        owner.ID.String(),
+			body:      UpdateDTO{Name: str("anonymous")},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusUnauthorized},
 		},
 	}
 	for _, tt := range tests {
@@ -144,93 +1165,241 @@ func TestHandler_CreateUser(t *testing.T) {
 			if err != nil {
 				t.Fatal(err)
 			}
-			req := httptest.NewRequest(http.MethodPost, "/user", bytes.NewReader(body))
+			req := httptest.NewRequest(http.MethodPatch, "/user/", bytes.NewReader(body))
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+				URLParams: chi.RouteParams{Keys: []string{"id"}, Values: []string{tt.id}},
+			})
+			if tt.claims != nil {
+				ctx = authmw.WithClaims(ctx, tt.claims)
+			}
+			req = req.WithContext(ctx)
 			w := httptest.NewRecorder()
 
-			err = h.CreateUser(w, req)
+			err = h.UpdateUser(w, req)
 			if tt.wantError == nil {
 				assert.NoError(t, err)
 				var dto DTO
-				err = json.NewDecoder(w.Body).Decode(&dto)
+				err := json.NewDecoder(w.Body).Decode(&dto)
 				if err != nil {
 					t.Fatal(err)
 				}
-				assert.NotEmpty(t, dto.ID)
-				assert.Equal(t, dto.Name, tt.body.Name)
-				assert.Equal(t, dto.Email, tt.body.Email)
+				assert.Equal(t, tt.wantName, dto.Name)
 			} else {
-				assert.Error(t, err)
 				var httpError *apperror.HTTPError
 				assert.ErrorAs(t, err, &httpError)
 				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+				if tt.wantError.Code != "" {
+					assert.Equal(t, tt.wantError.Code, httpError.Code)
+				}
 			}
 		})
 	}
 }
 
-func TestHandler_GetUser(t *testing.T) {
-	validID := uuid.New()
-	invalidID := uuid.New()
-	emptyID := ""
-	spaceID := "   "
-
-	service := createTestService(t, "valid", "password", "valid@email.test", validID)
-	h := &Handler{Service: service}
+func TestHandler_DeleteUser(t *testing.T) {
+	service, _ := newVerificationTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	owner, err := service.CreateNewUser(t.Context(), "owner", "owner@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := service.CreateNewUser(t.Context(), "other", "other@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsFor := func(id uuid.UUID, roles ...string) *authmw.Claims {
+		return &authmw.Claims{Roles: roles, RegisteredClaims: jwt.RegisteredClaims{Subject: id.String()}}
+	}
 
 	tests := []struct {
 		name      string
+		claims    *authmw.Claims
 		id        string
 		wantError *apperror.HTTPError
 	}{
 		{
-			name:      "valid id",
-			id:        validID.String(),
-			wantError: nil,
+			name:      "someone else",
+			claims:    claimsFor(other.ID),
+			id:        owner.ID.String(),
+			wantError: &apperror.HTTPError{StatusCode: http.StatusForbidden, Code: apperror.CodeUserModifyForbidden},
 		},
 		{
-			name:      "invalid id",
-			id:        invalidID.String(),
+			name:   "owner",
+			claims: claimsFor(owner.ID),
+			id:     owner.ID.String(),
+		},
+		{
+			name:      "already deleted",
+			claims:    claimsFor(other.ID, AdminRole),
+			id:        owner.ID.String(),
 			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound},
 		},
 		{
-			name:      "empty id",
-			id:        emptyID,
-			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+			name:   "admin",
+			claims: claimsFor(owner.ID, AdminRole),
+			id:     other.ID.String(),
 		},
 		{
-			name:      "id with spaces",
-			id:        spaceID,
-			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+			name:      "invalid id",
+			claims:    claimsFor(owner.ID, AdminRole),
+			id:        "invalid",
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest, Code: apperror.CodeInvalidUserID},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			req := httptest.NewRequest(http.MethodGet, "/user/", nil)
+			req := httptest.NewRequest(http.MethodDelete, "/user/", nil)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+				URLParams: chi.RouteParams{Keys: []string{"id"}, Values: []string{tt.id}},
+			})
+			req = req.WithContext(authmw.WithClaims(ctx, tt.claims))
 			w := httptest.NewRecorder()
 
-			chiCtx := &chi.Context{
-				URLParams: chi.RouteParams{
-					Keys:   []string{"id"},
-					Values: []string{tt.id},
-				},
+			err := h.DeleteUser(w, req)
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusNoContent, w.Code)
+			} else {
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+				if tt.wantError.Code != "" {
+					assert.Equal(t, tt.wantError.Code, httpError.Code)
+				}
 			}
-			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, chiCtx))
+		})
+	}
+}
 
-			err := h.GetUser(w, req)
+func TestHandler_RestoreUser(t *testing.T) {
+	service, _ := newVerificationTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	u, err := service.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeleteUser(t.Context(), u.ID, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	admin := &authmw.Claims{Roles: []string{AdminRole}, RegisteredClaims: jwt.RegisteredClaims{Subject: uuid.NewString()}}
+
+	tests := []struct {
+		name      string
+		id        string
+		wantError *apperror.HTTPError
+	}{
+		{name: "deleted user", id: u.ID.String()},
+		{name: "not deleted", id: u.ID.String(), wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound}},
+		{name: "unknown user", id: uuid.NewString(), wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound}},
+		{name: "invalid id", id: "invalid", wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/", nil)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, &chi.Context{
+				URLParams: chi.RouteParams{Keys: []string{"id"}, Values: []string{tt.id}},
+			})
+			req = req.WithContext(authmw.WithClaims(ctx, admin))
+			w := httptest.NewRecorder()
+
+			err := h.RestoreUser(w, req)
 			if tt.wantError == nil {
-				assert.NoError(t, err, "got error")
-				var dto DTO
+				assert.NoError(t, err)
+				assert.Equal(t, http.StatusNoContent, w.Code)
+			} else {
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHandler_ListUsers(t *testing.T) {
+	service, _ := newListTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantUsers int
+		wantNext  bool
+		wantTotal *int
+		wantError *apperror.HTTPError
+	}{
+		{name: "defaults", query: "", wantUsers: 5},
+		{name: "first page", query: "?limit=2&total=true", wantUsers: 2, wantNext: true, wantTotal: func() *int { n := 5; return &n }()},
+		{name: "filters", query: "?activated=false&joined_after=2024-01-02T00:00:00Z&order=desc", wantUsers: 2},
+		{name: "invalid limit", query: "?limit=zero", wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest, Code: apperror.CodeInvalidLimit}},
+		{name: "invalid cursor", query: "?cursor=garbage", wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest}},
+		{name: "invalid date", query: "?joined_before=yesterday", wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest}},
+		{name: "invalid order", query: "?order=random", wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			err := h.ListUsers(w, req)
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				var dto ListDTO
 				err := json.NewDecoder(w.Body).Decode(&dto)
 				if err != nil {
 					t.Fatal(err)
 				}
-				assert.NotEmpty(t, dto.ID)
-				assert.Equal(t, dto.ID, uuid.MustParse(tt.id))
+				assert.Len(t, dto.Users, tt.wantUsers)
+				assert.Equal(t, tt.wantNext, dto.NextCursor != "")
+				assert.Equal(t, tt.wantTotal, dto.Total)
+			} else {
+				var httpError *apperror.HTTPError
+				assert.ErrorAs(t, err, &httpError)
+				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+				if tt.wantError.Code != "" {
+					assert.Equal(t, tt.wantError.Code, httpError.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_FuzzySearchUsers(t *testing.T) {
+	service, _ := newListTestService(t)
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCount int
+		wantError *apperror.HTTPError
+	}{
+		{name: "matches", query: "?q=user", wantCount: 5},
+		{name: "limit", query: "?q=user&limit=2", wantCount: 2},
+		{name: "no match", query: "?q=nobody", wantCount: 0},
+		{name: "too short", query: "?q=us", wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest}},
+		{name: "invalid limit", query: "?q=user&limit=-1", wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest, Code: apperror.CodeInvalidLimit}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/search"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			err := h.FuzzySearchUsers(w, req)
+			if tt.wantError == nil {
+				assert.NoError(t, err)
+				var dto SearchResultsDTO
+				err := json.NewDecoder(w.Body).Decode(&dto)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Len(t, dto.Results, tt.wantCount)
 			} else {
-				assert.Error(t, err)
 				var httpError *apperror.HTTPError
 				assert.ErrorAs(t, err, &httpError)
 				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+				if tt.wantError.Code != "" {
+					assert.Equal(t, tt.wantError.Code, httpError.Code)
+				}
 			}
 		})
 	}
@@ -253,7 +1422,7 @@ func TestHandler_SearchUser(t *testing.T) {
 	badlyFormatedEmail := "invalid@email"
 
 	service := createTestService(t, validName, "password", validEmail)
-	h := &Handler{Service: service}
+	h := &Handler{Service: service, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
 
 	tests := []struct {
 		name      string
@@ -268,17 +1437,17 @@ func TestHandler_SearchUser(t *testing.T) {
 		{
 			name:      "invalid name",
 			body:      SearchDTO{Name: invalidName},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound, Code: apperror.CodeUserNotFound},
 		},
 		{
 			name:      "invalid email",
 			body:      SearchDTO{Email: invalidEmail},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound, Code: apperror.CodeUserNotFound},
 		},
 		{
 			name:      "empty name",
 			body:      SearchDTO{Name: emptyName},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusBadRequest, Code: apperror.CodeSearchCriteriaRequired},
 		},
 		{
 			name:      "empty email",
@@ -288,17 +1457,17 @@ func TestHandler_SearchUser(t *testing.T) {
 		{
 			name:      "name with spaces",
 			body:      SearchDTO{Name: spaceName},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound, Code: apperror.CodeUserNotFound},
 		},
 		{
 			name:      "email with spaces",
 			body:      SearchDTO{Email: spaceEmail},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound, Code: apperror.CodeUserNotFound},
 		},
 		{
 			name:      "badly formated email",
 			body:      SearchDTO{Email: badlyFormatedEmail},
-			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound},
+			wantError: &apperror.HTTPError{StatusCode: http.StatusNotFound, Code: apperror.CodeUserNotFound},
 		},
 	}
 	for _, tt := range tests {
@@ -310,6 +1479,14 @@ func TestHandler_SearchUser(t *testing.T) {
 			err := h.SearchUser(w, req)
 			if tt.wantError == nil {
 				assert.NoError(t, err, "got error")
+				var raw []map[string]any
+				if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+					t.Fatal(err)
+				}
+				for _, fields := range raw {
+					assert.NotContains(t, fields, "Disabled")
+					assert.NotContains(t, fields, "DeletedAt")
+				}
 				var dto []DTO
 				err := json.NewDecoder(w.Body).Decode(&dto)
 				if err != nil {
@@ -325,6 +1502,9 @@ func TestHandler_SearchUser(t *testing.T) {
 				var httpError *apperror.HTTPError
 				assert.ErrorAs(t, err, &httpError)
 				assert.Equal(t, tt.wantError.StatusCode, httpError.StatusCode)
+				if tt.wantError.Code != "" {
+					assert.Equal(t, tt.wantError.Code, httpError.Code)
+				}
 			}
 		})
 	}
@@ -342,7 +1522,7 @@ func createTestService(t *testing.T, username, password, email string, id ...uui
 	if err != nil {
 		t.Fatal(err)
 	}
-	return &InMemoryService{users: &InMemStore{
+	return newService(t, &InMemStore{
 		usersByID: map[uuid.UUID]*User{
 			userID: {
 				ID:    userID,
@@ -366,5 +1546,5 @@ func createTestService(t *testing.T, username, password, email string, id ...uui
 				Email: email,
 			},
 		},
-	}}
+	})
 }
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+type SortOrder string
+
+const (
+	SortAsc  SortOrder = "asc"
+	SortDesc SortOrder = "desc"
+)
+
+// ListFilter narrows a listing. Zero fields don't filter. The joined range
+// includes JoinedAfter and excludes JoinedBefore; prefixes are case
+// sensitive.
+type ListFilter struct {
+	Activated    *bool
+	JoinedAfter  *time.Time
+	JoinedBefore *time.Time
+	NamePrefix   string
+	EmailPrefix  string
+}
+
+// ListQuery selects a page of users ordered by (joined, id). After is the
+// cursor of the last user of the previous page.
+type ListQuery struct {
+	Filter    ListFilter
+	Order     SortOrder
+	Limit     int
+	After     *Cursor
+	WithTotal bool
+}
+
+// ListPage is one page of users. Next is nil on the last page and Total is
+// only set when the query asked for it.
+type ListPage struct {
+	Users []*User
+	Next  *Cursor
+	Total *int
+}
+
+// Cursor is a keyset position in a listing.
+type Cursor struct {
+	Joined time.Time `json:"j"`
+	ID     uuid.UUID `json:"i"`
+}
+
+func cursorOf(u *User) *Cursor {
+	return &Cursor{Joined: u.Joined, ID: u.ID}
+}
+
+// Encode returns the cursor as an opaque URL-safe string.
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	c := &Cursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.ID == uuid.Nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return c, nil
+}
+
+// normalize applies the defaults: ascending order and a limit of 50, capped
+// at 200. Stores call it before querying.
+func (q *ListQuery) normalize() error {
+	switch {
+	case q.Limit <= 0:
+		q.Limit = defaultListLimit
+	case q.Limit > maxListLimit:
+		q.Limit = maxListLimit
+	}
+	switch q.Order {
+	case "":
+		q.Order = SortAsc
+	case SortAsc, SortDesc:
+	default:
+		return fmt.Errorf("invalid sort order %q", q.Order)
+	}
+	return nil
+}
+
+func (us *InMemoryService) ListUsers(ctx context.Context, q ListQuery) (*ListPage, error) {
+	return us.users.List(ctx, q)
+}
@@ -0,0 +1,133 @@
+package user
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newListTestService returns a service with five users who joined a day
+// apart; the even ones are activated.
+func newListTestService(t *testing.T) (*InMemoryService, []*User) {
+	t.Helper()
+	us, _ := newVerificationTestService(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var users []*User
+	for i := range 5 {
+		u, err := NewUser(fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@email.test", i), "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.Joined = start.AddDate(0, 0, i)
+		u.Activated = i%2 == 0
+		if err := us.users.Add(t.Context(), u); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+	return us, users
+}
+
+func names(users []*User) []string {
+	out := make([]string, 0, len(users))
+	for _, u := range users {
+		out = append(out, u.Name)
+	}
+	return out
+}
+
+func TestInMemoryService_ListUsers_Pagination(t *testing.T) {
+	us, _ := newListTestService(t)
+
+	for _, order := range []SortOrder{SortAsc, SortDesc} {
+		t.Run(string(order), func(t *testing.T) {
+			var got []string
+			q := ListQuery{Order: order, Limit: 2, WithTotal: true}
+			for pages := 0; ; pages++ {
+				if pages > 5 {
+					t.Fatal("ListUsers() did not terminate")
+				}
+				page, err := us.ListUsers(t.Context(), q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if page.Total == nil || *page.Total != 5 {
+					t.Errorf("ListUsers() total = %v, want 5", page.Total)
+				}
+				got = append(got, names(page.Users)...)
+				if page.Next == nil {
+					break
+				}
+				q.After = page.Next
+			}
+			want := "[user0 user1 user2 user3 user4]"
+			if order == SortDesc {
+				want = "[user4 user3 user2 user1 user0]"
+			}
+			if fmt.Sprint(got) != want {
+				t.Errorf("ListUsers() pages = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestInMemoryService_ListUsers_Filter(t *testing.T) {
+	us, users := newListTestService(t)
+	activated := true
+	after := users[1].Joined
+	before := users[4].Joined
+	if err := us.DeleteUser(t.Context(), users[2].ID, users[2].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter ListFilter
+		want   string
+	}{
+		{name: "activated", filter: ListFilter{Activated: &activated}, want: "[user0 user4]"},
+		{name: "joined range", filter: ListFilter{JoinedAfter: &after, JoinedBefore: &before}, want: "[user1 user3]"},
+		{name: "name prefix", filter: ListFilter{NamePrefix: "user3"}, want: "[user3]"},
+		{name: "email prefix", filter: ListFilter{EmailPrefix: "user1@"}, want: "[user1]"},
+		{name: "no match", filter: ListFilter{NamePrefix: "nobody"}, want: "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := us.ListUsers(t.Context(), ListQuery{Filter: tt.filter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(names(page.Users)); got != tt.want {
+				t.Errorf("ListUsers() = %v, want %v", got, tt.want)
+			}
+			if page.Next != nil {
+				t.Errorf("ListUsers() returned a cursor for a single page")
+			}
+		})
+	}
+}
+
+func TestInMemoryService_ListUsers_Invalid(t *testing.T) {
+	us, _ := newListTestService(t)
+	if _, err := us.ListUsers(t.Context(), ListQuery{Order: "sideways"}); err == nil {
+		t.Errorf("ListUsers() accepted an unknown sort order")
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	c := &Cursor{Joined: time.Date(2024, 1, 1, 0, 0, 0, 123000, time.UTC), ID: uuid.New()}
+	got, err := DecodeCursor(c.Encode())
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	if !got.Joined.Equal(c.Joined) || got.ID != c.ID {
+		t.Errorf("DecodeCursor() = %+v, want %+v", got, c)
+	}
+	for _, s := range []string{"", "not base64!", "e30"} {
+		if _, err := DecodeCursor(s); err == nil {
+			t.Errorf("DecodeCursor(%q) should fail", s)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// mailTimeout bounds creating the token for a mail and delivering it.
+	mailTimeout = 30 * time.Second
+	// maxPendingMails bounds the mails being sent at once. Further mails are
+	// dropped and logged instead of piling up behind a slow relay.
+	maxPendingMails = 64
+)
+
+// sendInBackground runs send without waiting for it, so the caller answers
+// in the same time whether or not there was a mail to send. send keeps the
+// values of ctx but not its cancellation, and is given mailTimeout. Failures
+// are only logged.
+func (us *InMemoryService) sendInBackground(ctx context.Context, kind string, userID uuid.UUID, send func(context.Context) error) {
+	select {
+	case us.mailSlots <- struct{}{}:
+	default:
+		us.logger.ErrorContext(ctx, "Dropped mail, too many are pending", "mail", kind, "user", userID)
+		return
+	}
+	us.mailJobs.Add(1)
+	go func() {
+		defer us.mailJobs.Done()
+		defer func() { <-us.mailSlots }()
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mailTimeout)
+		defer cancel()
+		if err := send(ctx); err != nil {
+			us.logger.ErrorContext(ctx, "Failed to send mail", "mail", kind, "user", userID, "error", err)
+		}
+	}()
+}
+
+// WaitForMails blocks until the mails sent in the background are delivered
+// or have failed. Call it before closing the stores they write to.
+func (us *InMemoryService) WaitForMails() {
+	us.mailJobs.Wait()
+}
@@ -0,0 +1,62 @@
+package user
+
+import (
+	"awesomeProject/internal/audit"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangePassword replaces the password of a signed-in user after checking
+// their current one.
+func (us *InMemoryService) ChangePassword(ctx context.Context, id uuid.UUID, current, password string) error {
+	u, err := us.users.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !u.CheckPassword(current) {
+		return ErrWrongPassword
+	}
+	updated := *u
+	if err := updated.SetPassword(password); err != nil {
+		return err
+	}
+	if err := us.users.UpdatePassword(ctx, u.ID, updated.hash); err != nil {
+		return err
+	}
+	us.audit(audit.ActionPasswordChanged, u.ID, u.ID)
+	return nil
+}
+
+// SetPassword replaces the password of user id on behalf of actorID without
+// asking for the current one, and revokes the user's sessions.
+func (us *InMemoryService) SetPassword(ctx context.Context, actorID, id uuid.UUID, password string) error {
+	u, err := us.users.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	updated := *u
+	if err := updated.SetPassword(password); err != nil {
+		return err
+	}
+	if err := us.users.UpdatePassword(ctx, u.ID, updated.hash); err != nil {
+		return err
+	}
+	us.audit(audit.ActionPasswordReset, id, actorID)
+	return us.RevokeAllTokens(ctx, id)
+}
+
+// audit records an event. The action has already happened by the time it is
+// called, so failures are logged rather than returned.
+func (us *InMemoryService) audit(action audit.Action, userID, actorID uuid.UUID) {
+	err := us.auditor.Record(audit.Event{
+		Action:  action,
+		UserID:  userID.String(),
+		ActorID: actorID.String(),
+		Time:    time.Now(),
+	})
+	if err != nil {
+		us.logger.Error("failed to record audit event", "action", action, "user_id", userID, "error", err)
+	}
+}
@@ -0,0 +1,92 @@
+package user
+
+import (
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/mail"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ForgotPassword mails a password reset token. Unknown addresses and
+// disabled users are ignored so callers can't tell which emails have usable
+// accounts; the token and mail are done in the background for the same
+// reason.
+func (us *InMemoryService) ForgotPassword(ctx context.Context, email string) error {
+	u, err := us.users.GetByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if u.Disabled {
+		return nil
+	}
+	us.sendInBackground(ctx, "password reset", u.ID, func(ctx context.Context) error {
+		return us.sendPasswordReset(ctx, u)
+	})
+	return nil
+}
+
+func (us *InMemoryService) sendPasswordReset(ctx context.Context, u *User) error {
+	if err := us.actionTokens.InvalidateForUser(ctx, u.ID, PurposeResetPassword); err != nil {
+		return err
+	}
+	t, value, err := newActionToken(u.ID, PurposeResetPassword, passwordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	if err := us.actionTokens.Add(ctx, t); err != nil {
+		return err
+	}
+	return us.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset your password. If it was you, open the link below:\n\n%s%s\n\nThe link expires in %s. If you didn't ask for this you can ignore this mail.\n",
+			u.Name, us.resetURL, value, passwordResetTokenTTL,
+		),
+	})
+}
+
+// ResetPassword consumes a reset token, sets the new password and revokes
+// every existing session of the user.
+func (us *InMemoryService) ResetPassword(ctx context.Context, token, password string) error {
+	t, err := us.actionTokens.GetByHash(ctx, PurposeResetPassword, hashToken(token))
+	if errors.Is(err, ErrTokenNotFound) {
+		return fmt.Errorf("%w: unknown reset token", ErrInvalidToken)
+	}
+	if err != nil {
+		return err
+	}
+	if t.Used() || t.Expired(time.Now()) {
+		return fmt.Errorf("%w: reset token is no longer valid", ErrInvalidToken)
+	}
+	u, err := us.users.GetByID(ctx, t.UserID)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: user no longer exists", ErrInvalidToken)
+	}
+	if err != nil {
+		return err
+	}
+	// Check the policy before consuming the token so a rejected password
+	// can be retried with the same link.
+	updated := *u
+	if err := updated.SetPassword(password); err != nil {
+		return err
+	}
+	ok, err := us.actionTokens.Use(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: reset token is no longer valid", ErrInvalidToken)
+	}
+	if err := us.users.UpdatePassword(ctx, u.ID, updated.hash); err != nil {
+		return err
+	}
+	us.audit(audit.ActionPasswordReset, u.ID, u.ID)
+	return us.RevokeAllTokens(ctx, u.ID)
+}
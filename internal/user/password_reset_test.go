@@ -0,0 +1,117 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newPasswordResetTestService(t *testing.T) (*InMemoryService, *outbox, *User) {
+	t.Helper()
+	us, box := newVerificationTestService(t, WithPasswordResetURL("http://localhost/reset?token="))
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Let the verification mail go out before the tests mail reset links.
+	us.WaitForMails()
+	return us, box, u
+}
+
+func TestInMemoryService_ResetPassword(t *testing.T) {
+	us, box, _ := newPasswordResetTestService(t)
+	session, err := us.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.ForgotPassword(t.Context(), "valid@email.test"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	token := box.lastToken(t)
+
+	if err := us.ResetPassword(t.Context(), "unknown", "newpassword"); err == nil {
+		t.Errorf("ResetPassword() with an unknown token should fail")
+	}
+	if err := us.ResetPassword(t.Context(), token, "short"); err == nil {
+		t.Errorf("ResetPassword() should enforce the password policy")
+	}
+	if err := us.ResetPassword(t.Context(), token, "newpassword"); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if err := us.ResetPassword(t.Context(), token, "otherpassword"); err == nil {
+		t.Errorf("ResetPassword() should not accept a token twice")
+	}
+
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); err == nil {
+		t.Errorf("Authenticate() accepted the old password")
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "newpassword"); err != nil {
+		t.Errorf("Authenticate() error = %v with the new password", err)
+	}
+	if _, err := us.Refresh(t.Context(), session.RefreshToken); err == nil {
+		t.Errorf("Refresh() accepted a session issued before the reset")
+	}
+}
+
+func TestInMemoryService_ResetPassword_Expired(t *testing.T) {
+	us, _, u := newPasswordResetTestService(t)
+	tok, value, err := newActionToken(u.ID, PurposeResetPassword, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := us.actionTokens.Add(t.Context(), tok); err != nil {
+		t.Fatal(err)
+	}
+	if err := us.ResetPassword(t.Context(), value, "newpassword"); err == nil {
+		t.Errorf("ResetPassword() should reject an expired token")
+	}
+}
+
+func TestInMemoryService_ResetPassword_WrongPurpose(t *testing.T) {
+	us, box, _ := newPasswordResetTestService(t)
+	// The only mail so far is the verification link.
+	if err := us.ResetPassword(t.Context(), box.lastToken(t), "newpassword"); err == nil {
+		t.Errorf("ResetPassword() accepted an email verification token")
+	}
+}
+
+func TestInMemoryService_ForgotPassword(t *testing.T) {
+	us, box, u := newPasswordResetTestService(t)
+	sent := len(box.sent())
+
+	if err := us.ForgotPassword(t.Context(), "unknown@email.test"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v for an unknown address", err)
+	}
+	if len(box.sent()) != sent {
+		t.Errorf("ForgotPassword() mailed an unknown address")
+	}
+
+	if err := us.SetDisabled(t.Context(), uuid.Nil, u.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := us.ForgotPassword(t.Context(), "valid@email.test"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v for a disabled user", err)
+	}
+	if len(box.sent()) != sent {
+		t.Errorf("ForgotPassword() mailed a disabled user")
+	}
+	if err := us.SetDisabled(t.Context(), uuid.Nil, u.ID, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.ForgotPassword(t.Context(), "valid@email.test"); err != nil {
+		t.Fatal(err)
+	}
+	first := box.lastToken(t)
+	if err := us.ForgotPassword(t.Context(), "valid@email.test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := us.ResetPassword(t.Context(), first, "newpassword"); err == nil {
+		t.Errorf("ResetPassword() accepted a token superseded by a newer request")
+	}
+	if err := us.ResetPassword(t.Context(), box.lastToken(t), "newpassword"); err != nil {
+		t.Errorf("ResetPassword() error = %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"awesomeProject/internal/audit"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// auditLog is an audit.Recorder that keeps every event.
+type auditLog struct {
+	events []audit.Event
+}
+
+func (l *auditLog) Record(e audit.Event) error {
+	l.events = append(l.events, e)
+	return nil
+}
+
+func TestInMemoryService_ChangePassword(t *testing.T) {
+	events := &auditLog{}
+	us, _ := newVerificationTestService(t, WithAuditor(events))
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.ChangePassword(t.Context(), u.ID, "wrongpassword", "newpassword"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("ChangePassword() error = %v, want %v", err, ErrWrongPassword)
+	}
+	if err := us.ChangePassword(t.Context(), u.ID, "password", "short"); err == nil {
+		t.Errorf("ChangePassword() should enforce the password policy")
+	}
+	if len(events.events) != 0 {
+		t.Errorf("ChangePassword() recorded %v for rejected changes", events.events)
+	}
+
+	if err := us.ChangePassword(t.Context(), u.ID, "password", "newpassword"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); err == nil {
+		t.Errorf("Authenticate() accepted the old password")
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "newpassword"); err != nil {
+		t.Errorf("Authenticate() error = %v with the new password", err)
+	}
+
+	if len(events.events) != 1 {
+		t.Fatalf("ChangePassword() recorded %d events, want 1", len(events.events))
+	}
+	e := events.events[0]
+	if e.Action != audit.ActionPasswordChanged || e.UserID != u.ID.String() || e.ActorID != u.ID.String() {
+		t.Errorf("ChangePassword() recorded %+v", e)
+	}
+}
+
+func TestInMemoryService_SetPassword(t *testing.T) {
+	events := &auditLog{}
+	us, _ := newVerificationTestService(t, WithAuditor(events))
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := us.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.SetPassword(t.Context(), uuid.Nil, u.ID, "short"); err == nil {
+		t.Errorf("SetPassword() should enforce the password policy")
+	}
+	if err := us.SetPassword(t.Context(), uuid.Nil, u.ID, "newpassword"); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	tw, err := us.Authenticate(t.Context(), "valid@email.test", "newpassword")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v with the new password", err)
+	}
+	if _, err := us.Verify(t.Context(), tw.Token); err != nil {
+		t.Errorf("Verify() error = %v for a token issued right after SetPassword()", err)
+	}
+	if _, err := us.Refresh(t.Context(), session.RefreshToken); err == nil {
+		t.Errorf("Refresh() accepted a session issued before SetPassword()")
+	}
+	if len(events.events) != 1 || events.events[0].Action != audit.ActionPasswordReset || events.events[0].ActorID != uuid.Nil.String() {
+		t.Errorf("SetPassword() recorded %+v", events.events)
+	}
+}
@@ -0,0 +1,112 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresActionTokenStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresActionTokenStore(pool *pgxpool.Pool) *PostgresActionTokenStore {
+	return &PostgresActionTokenStore{
+		pool: pool,
+	}
+}
+
+func (s *PostgresActionTokenStore) Add(ctx context.Context, t *ActionToken) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO action_tokens (id, user_id, purpose, token_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := s.pool.Exec(
+		ctx,
+		query,
+		t.ID,
+		t.UserID,
+		t.Purpose,
+		t.hash,
+		t.CreatedAt,
+		t.ExpiresAt,
+	)
+	if err != nil {
+		return storeError("add token", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresActionTokenStore) GetByHash(ctx context.Context, purpose TokenPurpose, hash []byte) (*ActionToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, user_id, purpose, token_hash, created_at, expires_at, used_at
+		FROM action_tokens
+		WHERE purpose = $1 AND token_hash = $2
+	`
+
+	var t ActionToken
+	err := s.pool.QueryRow(ctx, query, purpose, hash).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Purpose,
+		&t.hash,
+		&t.CreatedAt,
+		&t.ExpiresAt,
+		&t.UsedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, storeError("get token", err)
+	}
+
+	return &t, nil
+}
+
+func (s *PostgresActionTokenStore) Use(ctx context.Context, id uuid.UUID) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE action_tokens
+		SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, query, id)
+	if err != nil {
+		return false, storeError("use token", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *PostgresActionTokenStore) InvalidateForUser(ctx context.Context, userID uuid.UUID, purpose TokenPurpose) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE action_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+
+	_, err := s.pool.Exec(ctx, query, userID, purpose)
+	if err != nil {
+		return storeError("invalidate tokens", err)
+	}
+
+	return nil
+}
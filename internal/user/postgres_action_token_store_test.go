@@ -0,0 +1,102 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPostgresActionTokenStore_Use(t *testing.T) {
+	pool := newTestPool(t)
+	u := addTestUser(t, NewPostgresStore(pool), pool)
+	s := NewPostgresActionTokenStore(pool)
+
+	at, _, err := newActionToken(u.ID, PurposeResetPassword, passwordResetTokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), at); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetByHash(t.Context(), PurposeVerifyEmail, at.hash); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("GetByHash() with another purpose error = %v, want ErrTokenNotFound", err)
+	}
+
+	if ok, err := s.Use(t.Context(), at.ID); err != nil || !ok {
+		t.Fatalf("Use() = %v, %v, want true, nil", ok, err)
+	}
+	if ok, err := s.Use(t.Context(), at.ID); err != nil || ok {
+		t.Errorf("second Use() = %v, %v, want false, nil", ok, err)
+	}
+	got, err := s.GetByHash(t.Context(), PurposeResetPassword, at.hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Used() {
+		t.Errorf("GetByHash() returned a used token that is not marked used")
+	}
+}
+
+func TestPostgresActionTokenStore_Expiry(t *testing.T) {
+	inZone(t)
+	pool := newTestPool(t)
+	u := addTestUser(t, NewPostgresStore(pool), pool)
+	s := NewPostgresActionTokenStore(pool)
+
+	live, _, err := newActionToken(u.ID, PurposeVerifyEmail, verificationTokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, err := newActionToken(u.ID, PurposeVerifyEmail, verificationTokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	for _, at := range []*ActionToken{live, expired} {
+		if err := s.Add(t.Context(), at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	got, err := s.GetByHash(t.Context(), PurposeVerifyEmail, live.hash)
+	if err != nil || got.Expired(now) {
+		t.Fatalf("GetByHash() of a live token = %+v, %v, want it unexpired", got, err)
+	}
+	if !got.ExpiresAt.Equal(live.ExpiresAt.Truncate(time.Microsecond)) {
+		t.Errorf("GetByHash() ExpiresAt = %v, want %v", got.ExpiresAt, live.ExpiresAt)
+	}
+	if got, err := s.GetByHash(t.Context(), PurposeVerifyEmail, expired.hash); err != nil || !got.Expired(now) {
+		t.Errorf("GetByHash() of an expired token = %+v, %v, want it expired", got, err)
+	}
+}
+
+func TestPostgresActionTokenStore_InvalidateForUser(t *testing.T) {
+	pool := newTestPool(t)
+	u := addTestUser(t, NewPostgresStore(pool), pool)
+	s := NewPostgresActionTokenStore(pool)
+
+	reset, _, err := newActionToken(u.ID, PurposeResetPassword, passwordResetTokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify, _, err := newActionToken(u.ID, PurposeVerifyEmail, verificationTokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range []*ActionToken{reset, verify} {
+		if err := s.Add(t.Context(), at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.InvalidateForUser(t.Context(), u.ID, PurposeResetPassword); err != nil {
+		t.Fatalf("InvalidateForUser() error = %v", err)
+	}
+	if ok, err := s.Use(t.Context(), reset.ID); err != nil || ok {
+		t.Errorf("Use() of an invalidated token = %v, %v, want false, nil", ok, err)
+	}
+	if ok, err := s.Use(t.Context(), verify.ID); err != nil || !ok {
+		t.Errorf("Use() of a token with another purpose = %v, %v, want true, nil", ok, err)
+	}
+}
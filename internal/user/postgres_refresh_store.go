@@ -0,0 +1,144 @@
+package user
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresRefreshStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRefreshStore(pool *pgxpool.Pool) *PostgresRefreshStore {
+	return &PostgresRefreshStore{
+		pool: pool,
+	}
+}
+
+func (s *PostgresRefreshStore) Add(ctx context.Context, t *RefreshToken) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, issued_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := s.pool.Exec(
+		ctx,
+		query,
+		t.ID,
+		t.FamilyID,
+		t.UserID,
+		t.hash,
+		t.IssuedAt,
+		t.ExpiresAt,
+	)
+	if err != nil {
+		return storeError("add refresh token", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresRefreshStore) GetByHash(ctx context.Context, hash []byte) (*RefreshToken, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT id, family_id, user_id, token_hash, issued_at, expires_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	var t RefreshToken
+	err := s.pool.QueryRow(ctx, query, hash).Scan(
+		&t.ID,
+		&t.FamilyID,
+		&t.UserID,
+		&t.hash,
+		&t.IssuedAt,
+		&t.ExpiresAt,
+		&t.RevokedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, storeError("get refresh token", err)
+	}
+
+	return &t, nil
+}
+
+func (s *PostgresRefreshStore) Revoke(ctx context.Context, id uuid.UUID) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// The revoked_at guard makes rotation atomic: of two concurrent refreshes
+	// with the same token only one sees a row updated.
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, query, id)
+	if err != nil {
+		return false, storeError("revoke refresh token", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *PostgresRefreshStore) RevokeFamily(ctx context.Context, familyID uuid.UUID) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := s.pool.Exec(ctx, query, familyID)
+	if err != nil {
+		return storeError("revoke refresh token family", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresRefreshStore) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	_, err := s.pool.Exec(ctx, query, userID)
+	if err != nil {
+		return storeError("revoke user refresh tokens", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresRefreshStore) DeleteExpired(ctx context.Context) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, storeError("delete expired refresh tokens", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,149 @@
+package user
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPostgresRefreshStore_Times(t *testing.T) {
+	inZone(t)
+	pool := newTestPool(t)
+	u := addTestUser(t, NewPostgresStore(pool), pool)
+	s := NewPostgresRefreshStore(pool)
+
+	rt, _, err := newRefreshToken(u.ID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), rt); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetByHash(t.Context(), rt.hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.ExpiresAt.Equal(rt.ExpiresAt.Truncate(time.Microsecond)) {
+		t.Errorf("GetByHash() ExpiresAt = %v, want %v", got.ExpiresAt, rt.ExpiresAt)
+	}
+	if got.Expired(time.Now()) {
+		t.Errorf("a fresh refresh token is already expired")
+	}
+}
+
+func TestPostgresRefreshStore_DeleteExpired(t *testing.T) {
+	inZone(t)
+	pool := newTestPool(t)
+	u := addTestUser(t, NewPostgresStore(pool), pool)
+	s := NewPostgresRefreshStore(pool)
+
+	live, _, err := newRefreshToken(u.ID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, err := newRefreshToken(u.ID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	for _, rt := range []*RefreshToken{live, expired} {
+		if err := s.Add(t.Context(), rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// DeleteExpired runs against the whole table, so only check our rows.
+	if _, err := s.DeleteExpired(t.Context()); err != nil {
+		t.Fatalf("DeleteExpired() error = %v", err)
+	}
+	if _, err := s.GetByHash(t.Context(), expired.hash); err == nil {
+		t.Errorf("DeleteExpired() kept an expired token")
+	}
+	if _, err := s.GetByHash(t.Context(), live.hash); err != nil {
+		t.Errorf("DeleteExpired() removed a live token: %v", err)
+	}
+}
+
+func TestPostgresRefreshStore_Revoke(t *testing.T) {
+	pool := newTestPool(t)
+	u := addTestUser(t, NewPostgresStore(pool), pool)
+	s := NewPostgresRefreshStore(pool)
+
+	rt, _, err := newRefreshToken(u.ID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), rt); err != nil {
+		t.Fatal(err)
+	}
+
+	// Of several refreshes racing with the same token exactly one may win.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	won := 0
+	for range 4 {
+		wg.Go(func() {
+			ok, err := s.Revoke(t.Context(), rt.ID)
+			if err != nil {
+				t.Errorf("Revoke() error = %v", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	if won != 1 {
+		t.Errorf("concurrent Revoke() succeeded %d times, want 1", won)
+	}
+	got, err := s.GetByHash(t.Context(), rt.hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Revoked() {
+		t.Errorf("GetByHash() returned a revoked token that is not marked revoked")
+	}
+}
+
+func TestPostgresRefreshStore_RevokeFamily(t *testing.T) {
+	pool := newTestPool(t)
+	u := addTestUser(t, NewPostgresStore(pool), pool)
+	s := NewPostgresRefreshStore(pool)
+
+	family := uuid.New()
+	inFamily, _, err := newRefreshToken(u.ID, family)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := newRefreshToken(u.ID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rt := range []*RefreshToken{inFamily, other} {
+		if err := s.Add(t.Context(), rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := s.RevokeFamily(t.Context(), family); err != nil {
+		t.Fatalf("RevokeFamily() error = %v", err)
+	}
+	if ok, err := s.Revoke(t.Context(), inFamily.ID); err != nil || ok {
+		t.Errorf("Revoke() of a token in a revoked family = %v, %v, want false, nil", ok, err)
+	}
+	if got, err := s.GetByHash(t.Context(), other.hash); err != nil || got.Revoked() {
+		t.Errorf("RevokeFamily() revoked a token outside the family: %+v, %v", got, err)
+	}
+
+	if err := s.RevokeAllForUser(t.Context(), u.ID); err != nil {
+		t.Fatalf("RevokeAllForUser() error = %v", err)
+	}
+	if got, err := s.GetByHash(t.Context(), other.hash); err != nil || !got.Revoked() {
+		t.Errorf("RevokeAllForUser() kept a token: %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,103 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresRevocationStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRevocationStore(pool *pgxpool.Pool) *PostgresRevocationStore {
+	return &PostgresRevocationStore{
+		pool: pool,
+	}
+}
+
+func (s *PostgresRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	_, err := s.pool.Exec(ctx, query, jti, expiresAt)
+	if err != nil {
+		return storeError("revoke token", err)
+	}
+
+	// Rows for tokens that expired on their own are no longer needed.
+	_, err = s.pool.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at < NOW()`)
+	if err != nil {
+		return storeError("prune revoked tokens", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)
+	`
+
+	var revoked bool
+	err := s.pool.QueryRow(ctx, query, jti).Scan(&revoked)
+	if err != nil {
+		return false, storeError("check token revocation", err)
+	}
+
+	return revoked, nil
+}
+
+func (s *PostgresRevocationStore) RevokeAllForUser(ctx context.Context, userID uuid.UUID, before time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO user_token_revocations (user_id, revoked_before)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET revoked_before = GREATEST(user_token_revocations.revoked_before, EXCLUDED.revoked_before)
+	`
+
+	_, err := s.pool.Exec(ctx, query, userID, before)
+	if err != nil {
+		return storeError("revoke user tokens", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresRevocationStore) RevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		SELECT revoked_before
+		FROM user_token_revocations
+		WHERE user_id = $1
+	`
+
+	var before time.Time
+	err := s.pool.QueryRow(ctx, query, userID).Scan(&before)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, storeError("read user token revocation", err)
+	}
+
+	return before, nil
+}
@@ -0,0 +1,71 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPostgresRevocationStore_RevokeAllForUser(t *testing.T) {
+	inZone(t)
+	pool := newTestPool(t)
+	u := addTestUser(t, NewPostgresStore(pool), pool)
+	s := NewPostgresRevocationStore(pool)
+
+	before := time.Now()
+	if err := s.RevokeAllForUser(t.Context(), u.ID, before); err != nil {
+		t.Fatalf("RevokeAllForUser() error = %v", err)
+	}
+	got, err := s.RevokedBefore(t.Context(), u.ID)
+	if err != nil {
+		t.Fatalf("RevokedBefore() error = %v", err)
+	}
+	if !got.Equal(before.Truncate(time.Microsecond)) {
+		t.Errorf("RevokedBefore() = %v, want %v", got, before)
+	}
+
+	// An older cutoff must not move the stored one back.
+	if err := s.RevokeAllForUser(t.Context(), u.ID, before.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.RevokedBefore(t.Context(), u.ID); !got.Equal(before.Truncate(time.Microsecond)) {
+		t.Errorf("RevokedBefore() after an older revoke-all = %v, want %v", got, before)
+	}
+
+	if got, err := s.RevokedBefore(t.Context(), uuid.New()); err != nil || !got.IsZero() {
+		t.Errorf("RevokedBefore() of a user without revocations = %v, %v, want zero", got, err)
+	}
+}
+
+func TestPostgresRevocationStore_Revoke(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresRevocationStore(pool)
+
+	jti := uuid.NewString()
+	if err := s.Revoke(t.Context(), jti, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	if err := s.Revoke(t.Context(), jti, time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("second Revoke() error = %v", err)
+	}
+	if revoked, err := s.IsRevoked(t.Context(), jti); err != nil || !revoked {
+		t.Errorf("IsRevoked() = %v, %v, want true, nil", revoked, err)
+	}
+	if revoked, err := s.IsRevoked(t.Context(), uuid.NewString()); err != nil || revoked {
+		t.Errorf("IsRevoked() of another token = %v, %v, want false, nil", revoked, err)
+	}
+
+	// Entries for tokens that expired on their own are pruned by the next
+	// Revoke.
+	expired := uuid.NewString()
+	if err := s.Revoke(t.Context(), expired, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revoke(t.Context(), uuid.NewString(), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := s.IsRevoked(t.Context(), expired); err != nil || revoked {
+		t.Errorf("IsRevoked() of an expired entry = %v, %v, want it pruned", revoked, err)
+	}
+}
@@ -2,12 +2,44 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// queryTimeout bounds every store query on top of the caller's context, so a
+// stuck query can't hold a request or a pool connection indefinitely.
+const queryTimeout = 5 * time.Second
+
+// uniqueViolation is the SQLSTATE Postgres reports for a unique constraint
+// failure.
+const uniqueViolation = "23505"
+
+func withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+// userWriteError maps a violation of the unique name or email constraint on
+// users to the matching domain error.
+func userWriteError(op string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		switch pgErr.ConstraintName {
+		case "users_name_key":
+			return ErrDuplicateName
+		case "users_email_key":
+			return ErrDuplicateEmail
+		}
+	}
+	return storeError(op, err)
+}
+
 type PostgresStore struct {
 	pool *pgxpool.Pool
 }
@@ -18,14 +50,17 @@ func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
 	}
 }
 
-func (s *PostgresStore) Add(u *User) error {
+func (s *PostgresStore) Add(ctx context.Context, u *User) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
 		INSERT INTO users (id, name, email, password_hash, joined, activated)
 		VALUES ($1, $2, $3, $4, $5, $6)
 	`
 
 	_, err := s.pool.Exec(
-		context.Background(),
+		ctx,
 		query,
 		u.ID,
 		u.Name,
@@ -35,77 +70,370 @@ func (s *PostgresStore) Add(u *User) error {
 		u.Activated,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to add user: %w", err)
+		return userWriteError("add user", err)
 	}
 
 	return nil
 }
 
-func (s *PostgresStore) GetByName(name string) (*User, error) {
+func (s *PostgresStore) GetByName(ctx context.Context, name string) (*User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, name, email, password_hash, joined, activated
+		SELECT id, name, email, password_hash, joined, activated, disabled
 		FROM users
-		WHERE name = $1
+		WHERE name = $1 AND deleted_at IS NULL
 	`
 
 	var u User
-	err := s.pool.QueryRow(context.Background(), query, name).Scan(
+	err := s.pool.QueryRow(ctx, query, name).Scan(
 		&u.ID,
 		&u.Name,
 		&u.Email,
 		&u.hash,
 		&u.Joined,
 		&u.Activated,
+		&u.Disabled,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, storeError("get user", err)
 	}
 
 	return &u, nil
 }
 
-func (s *PostgresStore) GetByID(id uuid.UUID) (*User, error) {
+func (s *PostgresStore) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, name, email, password_hash, joined, activated
+		SELECT id, name, email, password_hash, joined, activated, disabled
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	var u User
-	err := s.pool.QueryRow(context.Background(), query, id).Scan(
+	err := s.pool.QueryRow(ctx, query, id).Scan(
 		&u.ID,
 		&u.Name,
 		&u.Email,
 		&u.hash,
 		&u.Joined,
 		&u.Activated,
+		&u.Disabled,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, storeError("get user", err)
 	}
 
 	return &u, nil
 }
 
-func (s *PostgresStore) GetByEmail(email string) (*User, error) {
+func (s *PostgresStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
 	query := `
-		SELECT id, name, email, password_hash, joined, activated
+		SELECT id, name, email, password_hash, joined, activated, disabled
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND deleted_at IS NULL
 	`
 
 	var u User
-	err := s.pool.QueryRow(context.Background(), query, email).Scan(
+	err := s.pool.QueryRow(ctx, query, email).Scan(
 		&u.ID,
 		&u.Name,
 		&u.Email,
 		&u.hash,
 		&u.Joined,
 		&u.Activated,
+		&u.Disabled,
 	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, storeError("get user", err)
 	}
 
 	return &u, nil
 }
+
+func (s *PostgresStore) Activate(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET activated = TRUE
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, query, id)
+	if err != nil {
+		return storeError("activate user", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET disabled = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, query, id, disabled)
+	if err != nil {
+		return storeError("disable user", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) UpdatePassword(ctx context.Context, id uuid.UUID, hash []byte) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET password_hash = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, query, id, hash)
+	if err != nil {
+		return storeError("update password", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) Update(ctx context.Context, u *User) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET name = $2, email = $3, activated = $4
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, query, u.ID, u.Name, u.Email, u.Activated)
+	if err != nil {
+		return userWriteError("update user", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Delete soft-deletes the user. The row, and with it the name and email,
+// stays reserved until Purge removes it.
+func (s *PostgresStore) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET deleted_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	tag, err := s.pool.Exec(ctx, query, id)
+	if err != nil {
+		return storeError("delete user", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) Restore(ctx context.Context, id uuid.UUID, deletedAfter time.Time) error {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE users
+		SET deleted_at = NULL
+		WHERE id = $1 AND deleted_at > $2
+	`
+
+	tag, err := s.pool.Exec(ctx, query, id, deletedAfter)
+	if err != nil {
+		return storeError("restore user", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) Purge(ctx context.Context, deletedBefore time.Time) (int, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// Refresh tokens, revocation cutoffs and action tokens go with the user
+	// through ON DELETE CASCADE.
+	query := `
+		DELETE FROM users
+		WHERE deleted_at IS NOT NULL AND deleted_at <= $1
+	`
+
+	tag, err := s.pool.Exec(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, storeError("purge users", err)
+	}
+
+	return int(tag.RowsAffected()), nil
+}
+
+func (s *PostgresStore) List(ctx context.Context, q ListQuery) (*ListPage, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	where := []string{"deleted_at IS NULL"}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if q.Filter.Activated != nil {
+		where = append(where, "activated = "+arg(*q.Filter.Activated))
+	}
+	if q.Filter.JoinedAfter != nil {
+		where = append(where, "joined >= "+arg(*q.Filter.JoinedAfter))
+	}
+	if q.Filter.JoinedBefore != nil {
+		where = append(where, "joined < "+arg(*q.Filter.JoinedBefore))
+	}
+	if q.Filter.NamePrefix != "" {
+		where = append(where, "name LIKE "+arg(likePrefix(q.Filter.NamePrefix)))
+	}
+	if q.Filter.EmailPrefix != "" {
+		where = append(where, "email LIKE "+arg(likePrefix(q.Filter.EmailPrefix)))
+	}
+
+	page := &ListPage{Users: []*User{}}
+	if q.WithTotal {
+		// The total ignores the cursor so it stays the same on every page.
+		var total int
+		query := `SELECT COUNT(*) FROM users WHERE ` + strings.Join(where, " AND ")
+		err := s.pool.QueryRow(ctx, query, args...).Scan(&total)
+		if err != nil {
+			return nil, storeError("count users", err)
+		}
+		page.Total = &total
+	}
+
+	cmp, order := ">", "ASC"
+	if q.Order == SortDesc {
+		cmp, order = "<", "DESC"
+	}
+	if q.After != nil {
+		where = append(where, fmt.Sprintf("(joined, id) %s (%s, %s)", cmp, arg(q.After.Joined), arg(q.After.ID)))
+	}
+	// One extra row tells whether there is a next page.
+	query := `
+		SELECT id, name, email, password_hash, joined, activated, disabled
+		FROM users
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY joined ` + order + `, id ` + order + `
+		LIMIT ` + arg(q.Limit+1)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, storeError("list users", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u User
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.hash, &u.Joined, &u.Activated, &u.Disabled)
+		if err != nil {
+			return nil, storeError("scan user", err)
+		}
+		page.Users = append(page.Users, &u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storeError("list users", err)
+	}
+	if len(page.Users) > q.Limit {
+		page.Users = page.Users[:q.Limit]
+		page.Next = cursorOf(page.Users[q.Limit-1])
+	}
+
+	return page, nil
+}
+
+// likePrefix turns prefix into a LIKE pattern, escaping its wildcards.
+func likePrefix(prefix string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(prefix) + "%"
+}
+
+func (s *PostgresStore) Search(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	ctx, cancel := withQueryTimeout(ctx)
+	defer cancel()
+
+	// word_similarity scores how well query matches any part of the field, so
+	// "jon.smth@" still finds "john.smith@example.com". The <% operator
+	// applies pg_trgm.word_similarity_threshold and can use the trigram
+	// indexes.
+	sql := `
+		SELECT id, name, email, password_hash, joined, activated, disabled,
+			GREATEST(word_similarity($1, name), word_similarity($1, email)) AS score
+		FROM users
+		WHERE deleted_at IS NULL AND ($1 <% name OR $1 <% email)
+		ORDER BY score DESC, id
+		LIMIT $2
+	`
+
+	rows, err := s.pool.Query(ctx, sql, query, limit)
+	if err != nil {
+		return nil, storeError("search users", err)
+	}
+	defer rows.Close()
+	results := []SearchResult{}
+	for rows.Next() {
+		var u User
+		var score float32
+		err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.hash, &u.Joined, &u.Activated, &u.Disabled, &score)
+		if err != nil {
+			return nil, storeError("scan user", err)
+		}
+		results = append(results, SearchResult{User: &u, Score: float64(score)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, storeError("search users", err)
+	}
+
+	return results, nil
+}
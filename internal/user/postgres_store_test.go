@@ -0,0 +1,376 @@
+package user
+
+import (
+	"awesomeProject/internal/database"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newTestPool connects to the database in TEST_DATABASE_URL and runs the
+// migrations. Tests using it are skipped when the variable is unset.
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	if err := database.RunMigrations(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+// inZone runs the rest of the test with time.Local set to a zone far from
+// UTC, so times that lose their zone on the way through the database show up
+// as off by hours.
+func inZone(t *testing.T) {
+	t.Helper()
+	local := time.Local
+	time.Local = time.FixedZone("UTC-7", -7*60*60)
+	t.Cleanup(func() { time.Local = local })
+}
+
+// addTestUser stores a user with a unique name and removes it when the test
+// ends.
+func addTestUser(t *testing.T, s *PostgresStore, pool *pgxpool.Pool) *User {
+	t.Helper()
+	suffix := uuid.NewString()[:8]
+	u, err := NewUser("pg-"+suffix, "pg-"+suffix+"@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), u); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	})
+	return u
+}
+
+func TestPostgresStore_Add(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	u := addTestUser(t, s, pool)
+
+	byID, err := s.GetByID(t.Context(), u.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if byID.Name != u.Name || byID.Email != u.Email {
+		t.Errorf("GetByID() = %+v, want %+v", byID, u)
+	}
+	if _, err := s.GetByEmail(t.Context(), u.Email); err != nil {
+		t.Errorf("GetByEmail() error = %v", err)
+	}
+	if _, err := s.GetByName(t.Context(), u.Name); err != nil {
+		t.Errorf("GetByName() error = %v", err)
+	}
+	if err := s.Add(t.Context(), u); !errors.Is(err, ErrDuplicateName) && !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Add() of a duplicate user error = %v, want a duplicate error", err)
+	}
+}
+
+func TestPostgresStore_Activate(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	u := addTestUser(t, s, pool)
+
+	if err := s.Activate(t.Context(), u.ID); err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+	got, err := s.GetByID(t.Context(), u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Activated {
+		t.Errorf("Activate() did not activate the user")
+	}
+}
+
+func TestPostgresStore_SetDisabled(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	u := addTestUser(t, s, pool)
+
+	if err := s.SetDisabled(t.Context(), u.ID, true); err != nil {
+		t.Fatalf("SetDisabled() error = %v", err)
+	}
+	if err := s.Activate(t.Context(), u.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetByID(t.Context(), u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Disabled {
+		t.Errorf("Activate() cleared the disabled flag")
+	}
+	if err := s.SetDisabled(t.Context(), uuid.New(), true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetDisabled() of an unknown user error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestPostgresStore_UpdatePassword(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	u := addTestUser(t, s, pool)
+
+	updated := *u
+	if err := updated.SetPassword("newpassword"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdatePassword(t.Context(), u.ID, updated.hash); err != nil {
+		t.Fatalf("UpdatePassword() error = %v", err)
+	}
+	got, err := s.GetByID(t.Context(), u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.CheckPassword("newpassword") {
+		t.Errorf("UpdatePassword() did not change the password")
+	}
+	if err := s.UpdatePassword(t.Context(), uuid.New(), updated.hash); err == nil {
+		t.Errorf("UpdatePassword() of an unknown user should fail")
+	}
+}
+
+func TestPostgresStore_Update(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	u := addTestUser(t, s, pool)
+	other := addTestUser(t, s, pool)
+
+	taken := *u
+	taken.Email = other.Email
+	if err := s.Update(t.Context(), &taken); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Update() to a taken email error = %v, want %v", err, ErrDuplicateEmail)
+	}
+
+	updated := *u
+	updated.Name = u.Name + "-renamed"
+	updated.Activated = true
+	if err := s.Update(t.Context(), &updated); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	got, err := s.GetByID(t.Context(), u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != updated.Name || !got.Activated {
+		t.Errorf("Update() stored %+v, want %+v", got, updated)
+	}
+}
+
+func TestPostgresStore_Delete(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	u := addTestUser(t, s, pool)
+
+	if err := s.Delete(t.Context(), u.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.GetByID(t.Context(), u.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID() error = %v for a deleted user, want %v", err, ErrNotFound)
+	}
+	if _, err := s.GetByEmail(t.Context(), u.Email); err == nil {
+		t.Errorf("GetByEmail() returned a deleted user")
+	}
+	if err := s.Delete(t.Context(), u.ID); err == nil {
+		t.Errorf("Delete() of a deleted user should fail")
+	}
+
+	if err := s.Restore(t.Context(), u.ID, time.Now().Add(time.Hour)); err == nil {
+		t.Errorf("Restore() after the grace period should fail")
+	}
+	if err := s.Restore(t.Context(), u.ID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := s.GetByID(t.Context(), u.ID); err != nil {
+		t.Errorf("GetByID() error = %v after Restore()", err)
+	}
+}
+
+func TestPostgresStore_DeletionWindow(t *testing.T) {
+	inZone(t)
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	u := addTestUser(t, s, pool)
+
+	if err := s.Delete(t.Context(), u.ID); err != nil {
+		t.Fatal(err)
+	}
+	// deleted_at comes from the database clock and the cutoffs from Go, so
+	// both must agree on the instant whatever the local zone.
+	if _, err := s.Purge(t.Context(), time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(t.Context(), u.ID, time.Now().Add(time.Minute)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Restore() with a cutoff after the delete error = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Restore(t.Context(), u.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Errorf("Restore() within the window error = %v; Purge() may have removed the user early", err)
+	}
+}
+
+func TestPostgresStore_Purge(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	u := addTestUser(t, s, pool)
+	kept := addTestUser(t, s, pool)
+
+	refresh, _, err := newRefreshToken(u.ID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewPostgresRefreshStore(pool).Add(t.Context(), refresh); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(t.Context(), u.ID); err != nil {
+		t.Fatal(err)
+	}
+	// Purge runs against the whole table, so only check that our rows
+	// went away.
+	if _, err := s.Purge(t.Context(), time.Now()); err != nil {
+		t.Fatalf("Purge() error = %v", err)
+	}
+	if err := s.Restore(t.Context(), u.ID, time.Time{}); err == nil {
+		t.Errorf("Restore() of a purged user should fail")
+	}
+	if _, err := NewPostgresRefreshStore(pool).GetByHash(t.Context(), refresh.hash); err == nil {
+		t.Errorf("Purge() kept the refresh tokens of a purged user")
+	}
+	if _, err := s.GetByID(t.Context(), kept.ID); err != nil {
+		t.Errorf("Purge() removed a user that wasn't deleted")
+	}
+}
+
+func TestPostgresStore_List(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	// The table may hold other rows, so filter on a prefix only our users
+	// share.
+	prefix := "pg-list-" + uuid.NewString()[:8] + "-"
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		u, err := NewUser(fmt.Sprintf("%s%d", prefix, i), fmt.Sprintf("%s%d@email.test", prefix, i), "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.Joined = start.AddDate(0, 0, i)
+		u.Activated = i%2 == 0
+		if err := s.Add(t.Context(), u); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_, _ = pool.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+		})
+	}
+
+	for _, order := range []SortOrder{SortAsc, SortDesc} {
+		var got []string
+		q := ListQuery{Filter: ListFilter{NamePrefix: prefix}, Order: order, Limit: 2, WithTotal: true}
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatal("List() did not terminate")
+			}
+			page, err := s.List(t.Context(), q)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			if page.Total == nil || *page.Total != 5 {
+				t.Errorf("List() total = %v, want 5", page.Total)
+			}
+			for _, u := range page.Users {
+				got = append(got, strings.TrimPrefix(u.Name, prefix))
+			}
+			if page.Next == nil {
+				break
+			}
+			q.After = page.Next
+		}
+		want := "[0 1 2 3 4]"
+		if order == SortDesc {
+			want = "[4 3 2 1 0]"
+		}
+		if fmt.Sprint(got) != want {
+			t.Errorf("List(%s) pages = %v, want %v", order, got, want)
+		}
+	}
+
+	activated := false
+	page, err := s.List(t.Context(), ListQuery{Filter: ListFilter{NamePrefix: prefix, Activated: &activated}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Users) != 2 {
+		t.Errorf("List() with activated=false returned %d users, want 2", len(page.Users))
+	}
+	page, err = s.List(t.Context(), ListQuery{Filter: ListFilter{NamePrefix: "pg-list-%"}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Users) != 0 {
+		t.Errorf("List() treated %% in a prefix as a wildcard")
+	}
+}
+
+func TestPostgresStore_Search(t *testing.T) {
+	pool := newTestPool(t)
+	s := NewPostgresStore(pool)
+	suffix := uuid.NewString()[:8]
+	u, err := NewUser("john-"+suffix, "john.smith."+suffix+"@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), u); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = pool.Exec(context.Background(), `DELETE FROM users WHERE id = $1`, u.ID)
+	})
+
+	results, err := s.Search(t.Context(), "jon.smth."+suffix, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	var found *SearchResult
+	for i := range results {
+		if results[i].User.ID == u.ID {
+			found = &results[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("Search() = %v, want a match for a misspelt email", results)
+	}
+	if found.Score <= 0 || found.Score > 1 {
+		t.Errorf("Search() score = %v, want within (0, 1]", found.Score)
+	}
+
+	if err := s.Delete(t.Context(), u.ID); err != nil {
+		t.Fatal(err)
+	}
+	results, err = s.Search(t.Context(), "john.smith."+suffix, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.User.ID == u.ID {
+			t.Errorf("Search() returned a deleted user")
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package user
+
+import (
+	"awesomeProject/internal/audit"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UpdateUser changes the name and/or email of user id on behalf of actorID.
+// A new email has to be verified again, so it deactivates the account and
+// mails a fresh verification link.
+func (us *InMemoryService) UpdateUser(ctx context.Context, actorID, id uuid.UUID, update UpdateDTO) (*User, error) {
+	u, err := us.users.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	updated := *u
+	if update.Name != nil && *update.Name != u.Name {
+		name := strings.TrimSpace(*update.Name)
+		if err := validationError(nameViolations("name", name)); err != nil {
+			return nil, err
+		}
+		other, err := us.users.GetByName(ctx, name)
+		if err == nil && other.ID != id {
+			return nil, ErrDuplicateName
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		updated.Name = name
+	}
+	emailChanged := update.Email != nil && *update.Email != u.Email
+	if emailChanged {
+		if err := validationError(emailViolations("email", *update.Email)); err != nil {
+			return nil, err
+		}
+		other, err := us.users.GetByEmail(ctx, *update.Email)
+		if err == nil && other.ID != id {
+			return nil, ErrDuplicateEmail
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		updated.Email = *update.Email
+		updated.Activated = false
+	}
+	if err := us.users.Update(ctx, &updated); err != nil {
+		return nil, err
+	}
+	us.audit(audit.ActionUserUpdated, id, actorID)
+
+	if emailChanged {
+		us.sendVerification(ctx, &updated)
+	}
+	return &updated, nil
+}
+
+// DeleteUser soft-deletes user id on behalf of actorID and revokes their
+// sessions. The account can be restored for deletionGracePeriod.
+func (us *InMemoryService) DeleteUser(ctx context.Context, actorID, id uuid.UUID) error {
+	if _, err := us.users.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if err := us.RevokeAllTokens(ctx, id); err != nil {
+		return err
+	}
+	if err := us.users.Delete(ctx, id); err != nil {
+		return err
+	}
+	us.audit(audit.ActionUserDeleted, id, actorID)
+	return nil
+}
+
+// RestoreUser undoes a DeleteUser that happened within the grace period.
+// Sessions revoked by the delete stay revoked.
+func (us *InMemoryService) RestoreUser(ctx context.Context, actorID, id uuid.UUID) error {
+	if err := us.users.Restore(ctx, id, time.Now().Add(-deletionGracePeriod)); err != nil {
+		return err
+	}
+	us.audit(audit.ActionUserRestored, id, actorID)
+	return nil
+}
+
+// PurgeDeletedUsers permanently removes users whose grace period is over.
+func (us *InMemoryService) PurgeDeletedUsers(ctx context.Context) (int, error) {
+	return us.users.Purge(ctx, time.Now().Add(-deletionGracePeriod))
+}
+
+// PurgeExpiredRefreshTokens removes refresh tokens that can no longer be
+// used.
+func (us *InMemoryService) PurgeExpiredRefreshTokens(ctx context.Context) (int, error) {
+	return us.refreshTokens.DeleteExpired(ctx)
+}
+
+// RunPurge calls PurgeDeletedUsers and PurgeExpiredRefreshTokens every
+// interval until ctx is done.
+func (us *InMemoryService) RunPurge(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := us.PurgeDeletedUsers(ctx); err != nil {
+				us.logger.Error("Failed to purge deleted users", "error", err)
+			} else if n > 0 {
+				us.logger.Info("Purged deleted users", "count", n)
+			}
+			if n, err := us.PurgeExpiredRefreshTokens(ctx); err != nil {
+				us.logger.Error("Failed to purge expired refresh tokens", "error", err)
+			} else if n > 0 {
+				us.logger.Info("Purged expired refresh tokens", "count", n)
+			}
+		}
+	}
+}
+
+// SetActivated activates or deactivates user id on behalf of actorID.
+// Deactivating also revokes every session; it keeps the user from signing in
+// again only when activation is required, so use SetDisabled to lock a user
+// out.
+func (us *InMemoryService) SetActivated(ctx context.Context, actorID, id uuid.UUID, activated bool) error {
+	u, err := us.users.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	updated := *u
+	updated.Activated = activated
+	if err := us.users.Update(ctx, &updated); err != nil {
+		return err
+	}
+	if !activated {
+		if err := us.RevokeAllTokens(ctx, id); err != nil {
+			return err
+		}
+		us.audit(audit.ActionUserDeactivated, id, actorID)
+		return nil
+	}
+	us.audit(audit.ActionUserActivated, id, actorID)
+	return nil
+}
+
+// SetDisabled disables or enables user id on behalf of actorID. A disabled
+// user can't sign in, refresh or use an access token, and disabling revokes
+// every session.
+func (us *InMemoryService) SetDisabled(ctx context.Context, actorID, id uuid.UUID, disabled bool) error {
+	if err := us.users.SetDisabled(ctx, id, disabled); err != nil {
+		return err
+	}
+	if !disabled {
+		us.audit(audit.ActionUserEnabled, id, actorID)
+		return nil
+	}
+	if err := us.RevokeAllTokens(ctx, id); err != nil {
+		return err
+	}
+	us.audit(audit.ActionUserDisabled, id, actorID)
+	return nil
+}
@@ -0,0 +1,241 @@
+package user
+
+import (
+	"awesomeProject/internal/audit"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestInMemoryService_UpdateUser(t *testing.T) {
+	events := &auditLog{}
+	us, box := newVerificationTestService(t, WithAuditor(events))
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := us.VerifyEmail(t.Context(), box.lastToken(t)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := us.CreateNewUser(t.Context(), "other", "other@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+	str := func(s string) *string { return &s }
+
+	if _, err := us.UpdateUser(t.Context(), u.ID, u.ID, UpdateDTO{Name: str("other")}); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("UpdateUser() error = %v, want %v", err, ErrDuplicateName)
+	}
+	if _, err := us.UpdateUser(t.Context(), u.ID, u.ID, UpdateDTO{Email: str("other@email.test")}); !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("UpdateUser() error = %v, want %v", err, ErrDuplicateEmail)
+	}
+	if _, err := us.UpdateUser(t.Context(), u.ID, u.ID, UpdateDTO{Name: str("  ")}); err == nil {
+		t.Errorf("UpdateUser() accepted a blank name")
+	}
+
+	got, err := us.UpdateUser(t.Context(), u.ID, u.ID, UpdateDTO{Name: str("renamed")})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if got.Name != "renamed" || got.Email != "valid@email.test" || !got.Activated {
+		t.Errorf("UpdateUser() = %+v, want only the name changed", got)
+	}
+
+	sent := len(box.sent())
+	got, err = us.UpdateUser(t.Context(), u.ID, u.ID, UpdateDTO{Email: str("new@email.test")})
+	if err != nil {
+		t.Fatalf("UpdateUser() error = %v", err)
+	}
+	if got.Activated {
+		t.Errorf("UpdateUser() kept the account activated after an email change")
+	}
+	if len(box.sent()) != sent+1 || box.sent()[sent].To != "new@email.test" {
+		t.Fatalf("UpdateUser() did not mail the new address")
+	}
+	if err := us.VerifyEmail(t.Context(), box.lastToken(t)); err != nil {
+		t.Errorf("VerifyEmail() error = %v for the new address", err)
+	}
+
+	if len(events.events) != 2 || events.events[0].Action != audit.ActionUserUpdated {
+		t.Errorf("UpdateUser() recorded %+v, want two %s events", events.events, audit.ActionUserUpdated)
+	}
+}
+
+func TestInMemoryService_DeleteUser(t *testing.T) {
+	events := &auditLog{}
+	us, _ := newVerificationTestService(t, WithAuditor(events))
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := us.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.DeleteUser(t.Context(), u.ID, u.ID); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := us.GetUserByID(t.Context(), u.ID); err == nil {
+		t.Errorf("DeleteUser() left the user in place")
+	}
+	if _, err := us.Refresh(t.Context(), session.RefreshToken); err == nil {
+		t.Errorf("Refresh() accepted a session of a deleted user")
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); err == nil {
+		t.Errorf("Authenticate() accepted a deleted user")
+	}
+	if err := us.DeleteUser(t.Context(), u.ID, u.ID); err == nil {
+		t.Errorf("DeleteUser() of a deleted user should fail")
+	}
+	if len(events.events) != 1 || events.events[0].Action != audit.ActionUserDeleted {
+		t.Errorf("DeleteUser() recorded %+v", events.events)
+	}
+}
+
+func TestInMemoryService_RestoreUser(t *testing.T) {
+	events := &auditLog{}
+	us, _ := newVerificationTestService(t, WithAuditor(events))
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := uuid.New()
+
+	if err := us.RestoreUser(t.Context(), admin, u.ID); err == nil {
+		t.Errorf("RestoreUser() of a user that isn't deleted should fail")
+	}
+	if err := us.DeleteUser(t.Context(), u.ID, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := us.RestoreUser(t.Context(), admin, u.ID); err != nil {
+		t.Fatalf("RestoreUser() error = %v", err)
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); err != nil {
+		t.Errorf("Authenticate() error = %v after RestoreUser()", err)
+	}
+	last := events.events[len(events.events)-1]
+	if last.Action != audit.ActionUserRestored || last.ActorID != admin.String() {
+		t.Errorf("RestoreUser() recorded %+v", last)
+	}
+}
+
+func TestInMemoryService_PurgeDeletedUsers(t *testing.T) {
+	us, _ := newVerificationTestService(t)
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := us.DeleteUser(t.Context(), u.ID, u.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := us.PurgeDeletedUsers(t.Context()); err != nil || n != 0 {
+		t.Errorf("PurgeDeletedUsers() = %d, %v, want nothing purged inside the grace period", n, err)
+	}
+
+	// Backdate the deletion past the grace period.
+	deletedAt := time.Now().Add(-deletionGracePeriod - time.Minute)
+	us.users.(*InMemStore).usersByID[u.ID].DeletedAt = &deletedAt
+	if n, err := us.PurgeDeletedUsers(t.Context()); err != nil || n != 1 {
+		t.Fatalf("PurgeDeletedUsers() = %d, %v, want 1", n, err)
+	}
+	if err := us.RestoreUser(t.Context(), u.ID, u.ID); err == nil {
+		t.Errorf("RestoreUser() of a purged user should fail")
+	}
+}
+
+func TestInMemoryService_SetActivated(t *testing.T) {
+	events := &auditLog{}
+	us, _ := newVerificationTestService(t, WithAuditor(events), WithRequireActivation(true))
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.SetActivated(t.Context(), uuid.Nil, u.ID, true); err != nil {
+		t.Fatalf("SetActivated(true) error = %v", err)
+	}
+	session, err := us.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v after activation", err)
+	}
+
+	if err := us.SetActivated(t.Context(), uuid.Nil, u.ID, false); err != nil {
+		t.Fatalf("SetActivated(false) error = %v", err)
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); err == nil {
+		t.Errorf("Authenticate() accepted a disabled user")
+	}
+	if _, err := us.Refresh(t.Context(), session.RefreshToken); err == nil {
+		t.Errorf("Refresh() accepted a session of a disabled user")
+	}
+	if err := us.SetActivated(t.Context(), uuid.Nil, uuid.New(), true); err == nil {
+		t.Errorf("SetActivated() of an unknown user should fail")
+	}
+
+	want := []audit.Action{audit.ActionUserActivated, audit.ActionUserDeactivated}
+	if len(events.events) != len(want) {
+		t.Fatalf("SetActivated() recorded %+v, want %v", events.events, want)
+	}
+	for i, e := range events.events {
+		if e.Action != want[i] {
+			t.Errorf("event %d = %s, want %s", i, e.Action, want[i])
+		}
+	}
+}
+
+func TestInMemoryService_SetDisabled(t *testing.T) {
+	events := &auditLog{}
+	us, box := newVerificationTestService(t, WithAuditor(events))
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := us.Authenticate(t.Context(), "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.SetDisabled(t.Context(), uuid.Nil, u.ID, true); err != nil {
+		t.Fatalf("SetDisabled(true) error = %v", err)
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Authenticate() error = %v, want ErrDisabled", err)
+	}
+	if _, err := us.Refresh(t.Context(), session.RefreshToken); err == nil {
+		t.Errorf("Refresh() accepted a session of a disabled user")
+	}
+	if _, err := us.Verify(t.Context(), session.Token); err == nil {
+		t.Errorf("Verify() accepted an access token of a disabled user")
+	}
+
+	// Verifying the email must not undo the disable.
+	if err := us.VerifyEmail(t.Context(), box.lastToken(t)); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Authenticate() after VerifyEmail() error = %v, want ErrDisabled", err)
+	}
+
+	if err := us.SetDisabled(t.Context(), uuid.Nil, u.ID, false); err != nil {
+		t.Fatalf("SetDisabled(false) error = %v", err)
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); err != nil {
+		t.Errorf("Authenticate() error = %v after enabling", err)
+	}
+	if err := us.SetDisabled(t.Context(), uuid.Nil, uuid.New(), true); !errors.Is(err, ErrNotFound) {
+		t.Errorf("SetDisabled() of an unknown user error = %v, want ErrNotFound", err)
+	}
+
+	want := []audit.Action{audit.ActionUserDisabled, audit.ActionUserEnabled}
+	if len(events.events) != len(want) {
+		t.Fatalf("SetDisabled() recorded %+v, want %v", events.events, want)
+	}
+	for i, e := range events.events {
+		if e.Action != want[i] {
+			t.Errorf("event %d = %s, want %s", i, e.Action, want[i])
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// RefreshToken is an opaque, single-use token exchanged for a new access token.
+// Only the SHA-256 hash of the token value is kept. Every token issued from the
+// same login shares a FamilyID, so that replaying an already rotated token can
+// revoke the whole chain.
+type RefreshToken struct {
+	ID        uuid.UUID
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	hash      []byte
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+func newRefreshToken(userID, familyID uuid.UUID) (*RefreshToken, string, error) {
+	value, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	return &RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		hash:      hashToken(value),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	}, value, nil
+}
+
+func (t *RefreshToken) Revoked() bool {
+	return t.RevokedAt != nil
+}
+
+func (t *RefreshToken) Expired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
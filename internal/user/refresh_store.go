@@ -0,0 +1,105 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefreshStore interface {
+	Add(context.Context, *RefreshToken) error
+	GetByHash(context.Context, []byte) (*RefreshToken, error)
+	// Revoke marks the token as used. It reports false if the token had
+	// already been revoked, which callers must treat as reuse.
+	Revoke(context.Context, uuid.UUID) (bool, error)
+	RevokeFamily(context.Context, uuid.UUID) error
+	RevokeAllForUser(context.Context, uuid.UUID) error
+	// DeleteExpired removes tokens past their expiry and reports how many.
+	DeleteExpired(context.Context) (int, error)
+}
+
+type InMemRefreshStore struct {
+	mu     sync.Mutex
+	tokens map[string]*RefreshToken
+}
+
+func NewInMemRefreshStore() *InMemRefreshStore {
+	return &InMemRefreshStore{
+		tokens: make(map[string]*RefreshToken),
+	}
+}
+
+func (s *InMemRefreshStore) Add(_ context.Context, t *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[string(t.hash)] = t
+	return nil
+}
+
+func (s *InMemRefreshStore) GetByHash(_ context.Context, hash []byte) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tokens[string(hash)]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+func (s *InMemRefreshStore) Revoke(_ context.Context, id uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tokens {
+		if t.ID != id {
+			continue
+		}
+		if t.RevokedAt != nil {
+			return false, nil
+		}
+		now := time.Now()
+		t.RevokedAt = &now
+		return true, nil
+	}
+	return false, ErrTokenNotFound
+}
+
+func (s *InMemRefreshStore) RevokeFamily(_ context.Context, familyID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, t := range s.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *InMemRefreshStore) RevokeAllForUser(_ context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *InMemRefreshStore) DeleteExpired(_ context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	n := 0
+	for hash, t := range s.tokens {
+		if t.Expired(now) {
+			delete(s.tokens, hash)
+			n++
+		}
+	}
+	return n, nil
+}
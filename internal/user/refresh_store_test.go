@@ -0,0 +1,115 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestInMemRefreshStore_Revoke(t *testing.T) {
+	s := NewInMemRefreshStore()
+	rt, value, err := newRefreshToken(uuid.New(), uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), rt); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := s.Revoke(t.Context(), rt.ID)
+	if err != nil || !ok {
+		t.Fatalf("Revoke() = %v, %v, want true, nil", ok, err)
+	}
+	ok, err = s.Revoke(t.Context(), rt.ID)
+	if err != nil || ok {
+		t.Fatalf("second Revoke() = %v, %v, want false, nil", ok, err)
+	}
+
+	got, err := s.GetByHash(t.Context(), hashToken(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Revoked() {
+		t.Errorf("GetByHash() returned a token that is not revoked")
+	}
+
+	if _, err := s.Revoke(t.Context(), uuid.New()); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Revoke() of an unknown token error = %v, want ErrTokenNotFound", err)
+	}
+}
+
+func TestInMemRefreshStore_RevokeFamily(t *testing.T) {
+	s := NewInMemRefreshStore()
+	userID := uuid.New()
+	family := uuid.New()
+
+	var inFamily []*RefreshToken
+	for i := 0; i < 2; i++ {
+		rt, _, err := newRefreshToken(userID, family)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inFamily = append(inFamily, rt)
+		if err := s.Add(t.Context(), rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other, _, err := newRefreshToken(userID, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RevokeFamily(t.Context(), family); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rt := range inFamily {
+		got, err := s.GetByHash(t.Context(), rt.hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Revoked() {
+			t.Errorf("token %s in revoked family is still active", rt.ID)
+		}
+	}
+	got, err := s.GetByHash(t.Context(), other.hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Revoked() {
+		t.Errorf("token outside the family was revoked")
+	}
+}
+
+func TestInMemRefreshStore_DeleteExpired(t *testing.T) {
+	s := NewInMemRefreshStore()
+	live, _, err := newRefreshToken(uuid.New(), uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, _, err := newRefreshToken(uuid.New(), uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	for _, rt := range []*RefreshToken{live, expired} {
+		if err := s.Add(t.Context(), rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := s.DeleteExpired(t.Context()); err != nil || n != 1 {
+		t.Fatalf("DeleteExpired() = %d, %v, want 1", n, err)
+	}
+	if _, err := s.GetByHash(t.Context(), expired.hash); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("GetByHash() of an expired token error = %v, want ErrTokenNotFound", err)
+	}
+	if _, err := s.GetByHash(t.Context(), live.hash); err != nil {
+		t.Errorf("DeleteExpired() removed a live token: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package user
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevocationStore records access tokens that must be rejected before they
+// expire, either one at a time by jti or all tokens of a user issued before a
+// cutoff.
+type RevocationStore interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID, before time.Time) error
+	// RevokedBefore returns the cutoff set by RevokeAllForUser, or the zero
+	// time if there is none.
+	RevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error)
+}
+
+type InMemRevocationStore struct {
+	mu      sync.Mutex
+	tokens  map[string]time.Time
+	cutoffs map[uuid.UUID]time.Time
+}
+
+func NewInMemRevocationStore() *InMemRevocationStore {
+	return &InMemRevocationStore{
+		tokens:  make(map[string]time.Time),
+		cutoffs: make(map[uuid.UUID]time.Time),
+	}
+}
+
+func (s *InMemRevocationStore) Revoke(_ context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, exp := range s.tokens {
+		if exp.Before(now) {
+			delete(s.tokens, id)
+		}
+	}
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+func (s *InMemRevocationStore) IsRevoked(_ context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.tokens[jti]
+	return ok, nil
+}
+
+func (s *InMemRevocationStore) RevokeAllForUser(_ context.Context, userID uuid.UUID, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if before.After(s.cutoffs[userID]) {
+		s.cutoffs[userID] = before
+	}
+	return nil
+}
+
+func (s *InMemRevocationStore) RevokedBefore(_ context.Context, userID uuid.UUID) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cutoffs[userID], nil
+}
+
+// CachedRevocationStore fronts a shared store with a local cache. A revoked
+// jti never becomes valid again, so positive lookups are cached for good;
+// negative lookups and user cutoffs are only trusted for ttl so revocations
+// made by other replicas are seen quickly.
+type CachedRevocationStore struct {
+	store RevocationStore
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	valid   map[string]time.Time
+	cutoffs map[uuid.UUID]cachedCutoff
+}
+
+type cachedCutoff struct {
+	before  time.Time
+	fetched time.Time
+}
+
+func NewCachedRevocationStore(store RevocationStore, ttl time.Duration) *CachedRevocationStore {
+	return &CachedRevocationStore{
+		store:   store,
+		ttl:     ttl,
+		revoked: make(map[string]time.Time),
+		valid:   make(map[string]time.Time),
+		cutoffs: make(map[uuid.UUID]cachedCutoff),
+	}
+}
+
+func (c *CachedRevocationStore) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := c.store.Revoke(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.valid, jti)
+	c.revoked[jti] = expiresAt
+	return nil
+}
+
+func (c *CachedRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	now := time.Now()
+	c.mu.Lock()
+	if _, ok := c.revoked[jti]; ok {
+		c.mu.Unlock()
+		return true, nil
+	}
+	if checked, ok := c.valid[jti]; ok && now.Sub(checked) < c.ttl {
+		c.mu.Unlock()
+		return false, nil
+	}
+	c.mu.Unlock()
+
+	revoked, err := c.store.IsRevoked(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict(now)
+	if revoked {
+		// The expiry isn't known here; keep it for as long as any access
+		// token can live.
+		c.revoked[jti] = now.Add(accessTokenTTL)
+	} else {
+		c.valid[jti] = now
+	}
+	return revoked, nil
+}
+
+func (c *CachedRevocationStore) RevokeAllForUser(ctx context.Context, userID uuid.UUID, before time.Time) error {
+	if err := c.store.RevokeAllForUser(ctx, userID, before); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.cutoffs, userID)
+	return nil
+}
+
+func (c *CachedRevocationStore) RevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	now := time.Now()
+	c.mu.Lock()
+	if cached, ok := c.cutoffs[userID]; ok && now.Sub(cached.fetched) < c.ttl {
+		c.mu.Unlock()
+		return cached.before, nil
+	}
+	c.mu.Unlock()
+
+	before, err := c.store.RevokedBefore(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cutoffs[userID] = cachedCutoff{before: before, fetched: now}
+	return before, nil
+}
+
+func (c *CachedRevocationStore) evict(now time.Time) {
+	for jti, exp := range c.revoked {
+		if exp.Before(now) {
+			delete(c.revoked, jti)
+		}
+	}
+	for jti, checked := range c.valid {
+		if now.Sub(checked) >= c.ttl {
+			delete(c.valid, jti)
+		}
+	}
+	for id, cached := range c.cutoffs {
+		if now.Sub(cached.fetched) >= c.ttl {
+			delete(c.cutoffs, id)
+		}
+	}
+}
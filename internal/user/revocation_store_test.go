@@ -0,0 +1,96 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// countingRevocationStore counts lookups that reach the backing store.
+type countingRevocationStore struct {
+	*InMemRevocationStore
+	isRevokedCalls     int
+	revokedBeforeCalls int
+}
+
+func (c *countingRevocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	c.isRevokedCalls++
+	return c.InMemRevocationStore.IsRevoked(ctx, jti)
+}
+
+func (c *countingRevocationStore) RevokedBefore(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	c.revokedBeforeCalls++
+	return c.InMemRevocationStore.RevokedBefore(ctx, userID)
+}
+
+func TestInMemRevocationStore(t *testing.T) {
+	s := NewInMemRevocationStore()
+	if err := s.Revoke(t.Context(), "jti", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := s.IsRevoked(t.Context(), "jti"); !revoked {
+		t.Errorf("IsRevoked() = false for a revoked jti")
+	}
+	if revoked, _ := s.IsRevoked(t.Context(), "other"); revoked {
+		t.Errorf("IsRevoked() = true for an unknown jti")
+	}
+
+	userID := uuid.New()
+	later := time.Now()
+	earlier := later.Add(-time.Hour)
+	if err := s.RevokeAllForUser(t.Context(), userID, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeAllForUser(t.Context(), userID, earlier); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.RevokedBefore(t.Context(), userID); !got.Equal(later) {
+		t.Errorf("RevokedBefore() = %v, want the latest cutoff %v", got, later)
+	}
+	if got, _ := s.RevokedBefore(t.Context(), uuid.New()); !got.IsZero() {
+		t.Errorf("RevokedBefore() = %v for a user without cutoff", got)
+	}
+}
+
+func TestCachedRevocationStore(t *testing.T) {
+	backing := &countingRevocationStore{InMemRevocationStore: NewInMemRevocationStore()}
+	c := NewCachedRevocationStore(backing, time.Hour)
+
+	if revoked, _ := c.IsRevoked(t.Context(), "jti"); revoked {
+		t.Fatalf("IsRevoked() = true for an unknown jti")
+	}
+	c.IsRevoked(t.Context(), "jti")
+	if backing.isRevokedCalls != 1 {
+		t.Errorf("negative lookup hit the store %d times, want 1", backing.isRevokedCalls)
+	}
+
+	if err := c.Revoke(t.Context(), "jti", time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := c.IsRevoked(t.Context(), "jti"); !revoked {
+		t.Errorf("IsRevoked() = false after Revoke() through the cache")
+	}
+
+	// A revocation made elsewhere is seen once the negative entry expires.
+	backing.Revoke(t.Context(), "elsewhere", time.Now().Add(time.Minute))
+	short := NewCachedRevocationStore(backing, 0)
+	if revoked, _ := short.IsRevoked(t.Context(), "elsewhere"); !revoked {
+		t.Errorf("IsRevoked() = false for a jti revoked in the backing store")
+	}
+
+	userID := uuid.New()
+	c.RevokedBefore(t.Context(), userID)
+	c.RevokedBefore(t.Context(), userID)
+	if backing.revokedBeforeCalls != 1 {
+		t.Errorf("cutoff lookup hit the store %d times, want 1", backing.revokedBeforeCalls)
+	}
+	cutoff := time.Now()
+	if err := c.RevokeAllForUser(t.Context(), userID, cutoff); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := c.RevokedBefore(t.Context(), userID); !got.Equal(cutoff) {
+		t.Errorf("RevokedBefore() = %v after RevokeAllForUser(), want %v", got, cutoff)
+	}
+}
@@ -0,0 +1,45 @@
+package user
+
+import (
+	"awesomeProject/internal/apperror"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	minSearchLength    = 3
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchResult is a fuzzy search match. Score is between 0 and 1, higher is
+// closer.
+type SearchResult struct {
+	User  *User
+	Score float64
+}
+
+// FuzzySearchUsers finds users whose name or email resembles query. Queries
+// shorter than three characters are rejected since they have no trigrams to
+// match on.
+func (us *InMemoryService) FuzzySearchUsers(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < minSearchLength {
+		return nil, validationError([]Violation{{
+			Field:   "q",
+			Code:    apperror.CodeFieldTooShort,
+			Params:  map[string]string{"min": strconv.Itoa(minSearchLength)},
+			Message: fmt.Sprintf("must be at least %d characters", minSearchLength),
+		}})
+	}
+	switch {
+	case limit <= 0:
+		limit = defaultSearchLimit
+	case limit > maxSearchLimit:
+		limit = maxSearchLimit
+	}
+	return us.users.Search(ctx, query, limit)
+}
@@ -0,0 +1,43 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInMemoryService_FuzzySearchUsers(t *testing.T) {
+	us, _ := newVerificationTestService(t)
+	for _, u := range []struct{ name, email string }{
+		{"john", "john.smith@example.com"},
+		{"johnny", "johnny@example.com"},
+		{"jane", "jane@example.com"},
+	} {
+		if _, err := us.CreateNewUser(t.Context(), u.name, u.email, "password"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := us.FuzzySearchUsers(t.Context(), "JOHN", 0)
+	if err != nil {
+		t.Fatalf("FuzzySearchUsers() error = %v", err)
+	}
+	if len(got) != 2 || got[0].User.Name != "john" || got[1].User.Name != "johnny" {
+		t.Fatalf("FuzzySearchUsers() = %v, want john then johnny", got)
+	}
+	if got[0].Score != 1 || got[1].Score >= got[0].Score {
+		t.Errorf("FuzzySearchUsers() scores = %v, %v, want an exact match first", got[0].Score, got[1].Score)
+	}
+
+	got, err = us.FuzzySearchUsers(t.Context(), "example", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Errorf("FuzzySearchUsers() returned %d results, want the limit of 1", len(got))
+	}
+
+	var validationErr *ValidationError
+	if _, err := us.FuzzySearchUsers(t.Context(), " jo ", 0); !errors.As(err, &validationErr) {
+		t.Errorf("FuzzySearchUsers() error = %v for a query shorter than %d characters, want a *ValidationError", err, minSearchLength)
+	}
+}
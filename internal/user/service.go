@@ -1,11 +1,20 @@
 package user
 
 import (
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/mail"
+	"awesomeProject/internal/metrics"
+	"awesomeProject/internal/signing"
+	"awesomeProject/internal/telemetry"
+	"awesomeProject/pkg/authmw"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -13,127 +22,644 @@ import (
 )
 
 type Service interface {
-	GetUserByName(name string) (*User, error)
-	GetUserByID(id uuid.UUID) (*User, error)
-	GetUserByEmail(email string) (*User, error)
-	CreateNewUser(name, email, password string) (*User, error)
-	Authenticate(email, password string) (string, error)
+	GetUserByName(ctx context.Context, name string) (*User, error)
+	GetUserByID(ctx context.Context, id uuid.UUID) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	CreateNewUser(ctx context.Context, name, email, password string) (*User, error)
+	CreateActivatedUser(ctx context.Context, actorID uuid.UUID, name, email, password string) (*User, error)
+	Authenticate(ctx context.Context, email, password string) (*TokenWrapper, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenWrapper, error)
+	Logout(ctx context.Context, accessToken, refreshToken string) error
+	RevokeToken(ctx context.Context, token, tokenTypeHint string) error
+	RevokeAllTokens(ctx context.Context, userID uuid.UUID) error
+	Introspect(ctx context.Context, token, tokenTypeHint string) (*Introspection, error)
+	Verify(ctx context.Context, token string) (*authmw.Claims, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	ChangePassword(ctx context.Context, id uuid.UUID, current, password string) error
+	UpdateUser(ctx context.Context, actorID, id uuid.UUID, update UpdateDTO) (*User, error)
+	DeleteUser(ctx context.Context, actorID, id uuid.UUID) error
+	RestoreUser(ctx context.Context, actorID, id uuid.UUID) error
+	ListUsers(context.Context, ListQuery) (*ListPage, error)
+	FuzzySearchUsers(ctx context.Context, query string, limit int) ([]SearchResult, error)
+	SetActivated(ctx context.Context, actorID, id uuid.UUID, activated bool) error
+	SetDisabled(ctx context.Context, actorID, id uuid.UUID, disabled bool) error
+	SetPassword(ctx context.Context, actorID, id uuid.UUID, password string) error
 }
 
+// ErrWrongPassword is returned by ChangePassword when the current password
+// doesn't match.
+var ErrWrongPassword = errors.New("current password is incorrect")
+
+const (
+	accessTokenTTL = 15 * time.Minute
+	tokenIssuer    = "auth-service"
+	// deletionGracePeriod is how long a deleted account can be restored
+	// before it is purged.
+	deletionGracePeriod = 30 * 24 * time.Hour
+	defaultRolesURL     = "http://localhost:4001"
+)
+
 type InMemoryService struct {
-	users Store
+	users         Store
+	refreshTokens RefreshStore
+	keys          signing.KeySource
+	revocations   RevocationStore
+	actionTokens  ActionTokenStore
+	mailer        mail.Sender
+	auditor       audit.Recorder
+	// verifyURL is the link mailed for email verification; the token is
+	// appended to it.
+	verifyURL string
+	// resetURL is the link mailed for password resets; the token is
+	// appended to it.
+	resetURL string
+	// requireActivation makes Authenticate refuse users that haven't
+	// verified their email.
+	requireActivation bool
+	// rolesURL is the base URL of the roles service.
+	rolesURL string
+	logger   *slog.Logger
+
+	// mailSlots and mailJobs track the mails sent by sendInBackground.
+	mailSlots chan struct{}
+	mailJobs  sync.WaitGroup
+}
+
+type Option func(*InMemoryService)
+
+// WithRefreshStore sets where refresh tokens are persisted. Defaults to an
+// InMemRefreshStore.
+func WithRefreshStore(s RefreshStore) Option {
+	return func(us *InMemoryService) {
+		us.refreshTokens = s
+	}
+}
+
+// WithSigningKeys sets the keys access tokens are signed with. It is
+// required.
+func WithSigningKeys(keys signing.KeySource) Option {
+	return func(us *InMemoryService) {
+		us.keys = keys
+	}
+}
+
+// WithRevocationStore sets where revoked access tokens are recorded. Defaults
+// to an InMemRevocationStore.
+func WithRevocationStore(s RevocationStore) Option {
+	return func(us *InMemoryService) {
+		us.revocations = s
+	}
+}
+
+// WithActionTokenStore sets where verification tokens are persisted. Defaults
+// to an InMemActionTokenStore.
+func WithActionTokenStore(s ActionTokenStore) Option {
+	return func(us *InMemoryService) {
+		us.actionTokens = s
+	}
+}
+
+// WithMailer sets how verification mails are sent and the link they contain.
+// Defaults to logging mails through the service logger.
+func WithMailer(sender mail.Sender, verifyURL string) Option {
+	return func(us *InMemoryService) {
+		us.mailer = sender
+		us.verifyURL = verifyURL
+	}
+}
+
+// WithPasswordResetURL sets the link mailed for password resets.
+func WithPasswordResetURL(resetURL string) Option {
+	return func(us *InMemoryService) {
+		us.resetURL = resetURL
+	}
 }
 
-func NewInMemoryUserService(users Store) *InMemoryService {
-	return &InMemoryService{
-		users: users,
+// WithAuditor sets where audit events are recorded. Defaults to the logger.
+func WithAuditor(r audit.Recorder) Option {
+	return func(us *InMemoryService) {
+		us.auditor = r
 	}
 }
 
-type jwtCustomClaims struct {
-	Roles []string `json:"roles"`
-	jwt.RegisteredClaims
+// WithRolesURL sets the base URL of the roles service that access token roles
+// are fetched from. An empty URL issues tokens without roles.
+func WithRolesURL(url string) Option {
+	return func(us *InMemoryService) {
+		us.rolesURL = url
+	}
+}
+
+// WithRequireActivation makes Authenticate refuse users that haven't verified
+// their email yet.
+func WithRequireActivation(require bool) Option {
+	return func(us *InMemoryService) {
+		us.requireActivation = require
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(us *InMemoryService) {
+		us.logger = logger
+	}
 }
 
-func (us *InMemoryService) Authenticate(identifier, password string) (string, error) {
+// NewInMemoryUserService returns an error when no signing keys are given with
+// WithSigningKeys.
+func NewInMemoryUserService(users Store, opts ...Option) (*InMemoryService, error) {
+	us := &InMemoryService{
+		users:         users,
+		refreshTokens: NewInMemRefreshStore(),
+		revocations:   NewInMemRevocationStore(),
+		actionTokens:  NewInMemActionTokenStore(),
+		rolesURL:      defaultRolesURL,
+		logger:        slog.Default(),
+		mailSlots:     make(chan struct{}, maxPendingMails),
+	}
+	for _, opt := range opts {
+		opt(us)
+	}
+	if us.keys == nil {
+		return nil, errors.New("no signing keys configured")
+	}
+	if us.mailer == nil {
+		us.mailer = mail.NewLogSender(us.logger)
+	}
+	if us.auditor == nil {
+		us.auditor = audit.NewLogRecorder(us.logger)
+	}
+	return us, nil
+}
+
+// jwtCustomClaims is shared with pkg/authmw so consuming services verify
+// exactly the shape issued here.
+type jwtCustomClaims = authmw.Claims
+
+func (us *InMemoryService) Authenticate(ctx context.Context, identifier, password string) (*TokenWrapper, error) {
 	var u *User
 	var err error
 	if isEmail(identifier) {
-		u, err = us.users.GetByEmail(identifier)
+		u, err = us.users.GetByEmail(ctx, identifier)
 	} else {
-		u, err = us.users.GetByName(identifier)
+		u, err = us.users.GetByName(ctx, identifier)
+	}
+	if errors.Is(err, ErrNotFound) {
+		metrics.Authentications.WithLabelValues(metrics.AuthUnknownUser).Inc()
+		return nil, ErrInvalidCredentials
 	}
 	if err != nil {
-		return "", fmt.Errorf("can't authenticate user: %v", err)
+		metrics.Authentications.WithLabelValues(metrics.AuthError).Inc()
+		return nil, err
 	}
 	if !u.CheckPassword(password) {
-		return "", fmt.Errorf("invalid password")
+		metrics.Authentications.WithLabelValues(metrics.AuthBadPassword).Inc()
+		return nil, ErrInvalidCredentials
 	}
+	if u.Disabled {
+		metrics.Authentications.WithLabelValues(metrics.AuthDisabled).Inc()
+		return nil, ErrDisabled
+	}
+	if us.requireActivation && !u.Activated {
+		metrics.Authentications.WithLabelValues(metrics.AuthInactive).Inc()
+		return nil, ErrNotActivated
+	}
+	tw, err := us.issueTokens(ctx, u, uuid.New())
+	if err != nil {
+		metrics.Authentications.WithLabelValues(metrics.AuthError).Inc()
+		return nil, err
+	}
+	metrics.Authentications.WithLabelValues(metrics.AuthSuccess).Inc()
+	return tw, nil
+}
 
-	// Try to fetch roles, but don't fail if roles service is unavailable
-	roleNames := make([]string, 0)
-	resp, err := http.Get("http://localhost:4001/users/" + u.ID.String() + "/roles")
-	if err == nil {
-		defer resp.Body.Close()
-
-		var roles []struct {
-			ID   uuid.UUID `json:"id"`
-			Name string    `json:"name"`
-		}
+func (us *InMemoryService) Refresh(ctx context.Context, refreshToken string) (*TokenWrapper, error) {
+	rt, err := us.refreshTokens.GetByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, fmt.Errorf("%w: unknown refresh token", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rt.Revoked() {
+		return nil, us.revokeReusedFamily(ctx, rt)
+	}
+	if rt.Expired(time.Now()) {
+		return nil, fmt.Errorf("%w: refresh token expired", ErrInvalidToken)
+	}
+	ok, err := us.refreshTokens.Revoke(ctx, rt.ID)
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, fmt.Errorf("%w: unknown refresh token", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Lost a race against another refresh with the same token.
+		return nil, us.revokeReusedFamily(ctx, rt)
+	}
+	u, err := us.users.GetByID(ctx, rt.UserID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: user no longer exists", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u.Disabled {
+		return nil, ErrDisabled
+	}
+	return us.issueTokens(ctx, u, rt.FamilyID)
+}
 
-		err = json.NewDecoder(resp.Body).Decode(&roles)
-		if err == nil {
-			roleNames = make([]string, 0, len(roles))
-			for _, role := range roles {
-				roleNames = append(roleNames, role.Name)
-			}
-		}
+// revokeReusedFamily is called when an already rotated refresh token is
+// presented again. The token has leaked, so every token descended from the
+// same login is revoked.
+func (us *InMemoryService) revokeReusedFamily(ctx context.Context, rt *RefreshToken) error {
+	if err := us.refreshTokens.RevokeFamily(ctx, rt.FamilyID); err != nil {
+		return err
 	}
+	return fmt.Errorf("%w: refresh token reuse detected", ErrInvalidToken)
+}
 
-	t, err := issueSignedToken(u, roleNames)
+func (us *InMemoryService) issueTokens(ctx context.Context, u *User, familyID uuid.UUID) (*TokenWrapper, error) {
+	t, err := issueSignedToken(us.keys, u, us.fetchRoles(ctx, u.ID))
+	if err != nil {
+		return nil, err
+	}
+	rt, value, err := newRefreshToken(u.ID, familyID)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if err := us.refreshTokens.Add(ctx, rt); err != nil {
+		return nil, err
 	}
-	return t, nil
+	metrics.TokensIssued.WithLabelValues("access").Inc()
+	metrics.TokensIssued.WithLabelValues("refresh").Inc()
+	return &TokenWrapper{
+		Token:        t,
+		RefreshToken: value,
+		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+	}, nil
 }
 
-func issueSignedToken(user *User, roles []string) (string, error) {
-	secret, ok := os.LookupEnv("SIGN_KEY")
-	if !ok {
-		return "", fmt.Errorf("SIGN_KEY not set")
+// rolesClient traces calls to the roles service.
+var rolesClient = &http.Client{
+	Transport: telemetry.Transport(http.DefaultTransport),
+	Timeout:   5 * time.Second,
+}
+
+// fetchRoles asks the roles service for the user's roles, but doesn't fail if
+// the roles service is unavailable or not configured.
+func (us *InMemoryService) fetchRoles(ctx context.Context, id uuid.UUID) []string {
+	roleNames := make([]string, 0)
+	if us.rolesURL == "" {
+		return roleNames
 	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, us.rolesURL+"/users/"+id.String()+"/roles", nil)
+	if err != nil {
+		return roleNames
+	}
+	resp, err := rolesClient.Do(req)
+	if err != nil {
+		return roleNames
+	}
+	defer resp.Body.Close()
+
+	var roles []struct {
+		ID   uuid.UUID `json:"id"`
+		Name string    `json:"name"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&roles)
+	if err != nil {
+		return roleNames
+	}
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+	return roleNames
+}
+
+func issueSignedToken(keys signing.KeySource, user *User, roles []string) (string, error) {
 	if user == nil {
 		return "", fmt.Errorf("user is nil")
 	}
 	claims := jwtCustomClaims{
 		Roles: roles,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 24)),
-			Issuer:    "auth-service",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
+			Issuer:    tokenIssuer,
 			Subject:   user.ID.String(),
 		},
 	}
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString([]byte(secret))
+	token, err := signing.Sign(keys, claims)
+	if errors.Is(err, signing.ErrKeysUnavailable) {
+		return "", storeError("load signing keys", err)
+	}
+	return token, err
+}
+
+// Verify checks an access token's signature and expiry, that it hasn't been
+// revoked, individually or by a revoke-all for its subject, and that its
+// subject still exists and isn't disabled. It makes the service an
+// authmw.Verifier: errors other than ErrInvalidToken also match
+// authmw.ErrUnavailable, since they mean the token couldn't be checked.
+func (us *InMemoryService) Verify(ctx context.Context, token string) (*jwtCustomClaims, error) {
+	claims, _, err := us.verify(ctx, token)
+	if err != nil && !errors.Is(err, ErrInvalidToken) {
+		return nil, fmt.Errorf("%w: %w", authmw.ErrUnavailable, err)
+	}
+	return claims, err
 }
 
-func (us *InMemoryService) GetUserByName(name string) (*User, error) {
-	u, err := us.users.GetByName(name)
+// verify does the checks of Verify and also returns the subject it loaded.
+func (us *InMemoryService) verify(ctx context.Context, token string) (*jwtCustomClaims, *User, error) {
+	claims := &jwtCustomClaims{}
+	_, err := jwt.ParseWithClaims(
+		token,
+		claims,
+		signing.Keyfunc(us.keys),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if errors.Is(err, signing.ErrKeysUnavailable) {
+		return nil, nil, storeError("load signing keys", err)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.ID != "" {
+		revoked, err := us.revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if revoked {
+			return nil, nil, fmt.Errorf("%w: token has been revoked", ErrInvalidToken)
+		}
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid subject", ErrInvalidToken)
+	}
+	before, err := us.revocations.RevokedBefore(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !before.IsZero() && (claims.IssuedAt == nil || claims.IssuedAt.Before(before)) {
+		return nil, nil, fmt.Errorf("%w: token has been revoked", ErrInvalidToken)
+	}
+	u, err := us.users.GetByID(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil, fmt.Errorf("%w: user no longer exists", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.Disabled {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidToken, ErrDisabled)
+	}
+	return claims, u, nil
+}
+
+// Logout revokes the presented access token and, if given, the refresh token
+// family it was issued with.
+func (us *InMemoryService) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	claims, err := us.Verify(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	if err := us.revokeAccessToken(ctx, claims); err != nil {
+		return err
+	}
+	if refreshToken == "" {
+		return nil
+	}
+	rt, err := us.refreshTokens.GetByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, ErrTokenNotFound) {
+		return fmt.Errorf("%w: unknown refresh token", ErrInvalidToken)
+	}
+	if err != nil {
+		return err
+	}
+	if rt.UserID.String() != claims.Subject {
+		return fmt.Errorf("%w: refresh token belongs to another user", ErrInvalidToken)
+	}
+	return us.refreshTokens.RevokeFamily(ctx, rt.FamilyID)
+}
+
+// RevokeToken implements RFC 7009. Refresh tokens revoke their whole family;
+// access tokens are added to the revocation list. Unknown or invalid tokens
+// are not an error, as the RFC requires.
+func (us *InMemoryService) RevokeToken(ctx context.Context, token, tokenTypeHint string) error {
+	revokeRefresh := func() (bool, error) {
+		rt, err := us.refreshTokens.GetByHash(ctx, hashToken(token))
+		if errors.Is(err, ErrTokenNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, us.refreshTokens.RevokeFamily(ctx, rt.FamilyID)
+	}
+	revokeAccess := func() (bool, error) {
+		claims, err := us.Verify(ctx, token)
+		if errors.Is(err, ErrInvalidToken) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, us.revokeAccessToken(ctx, claims)
+	}
+
+	attempts := []func() (bool, error){revokeAccess, revokeRefresh}
+	if tokenTypeHint == "refresh_token" {
+		attempts = []func() (bool, error){revokeRefresh, revokeAccess}
+	}
+	for _, attempt := range attempts {
+		done, err := attempt()
+		if err != nil || done {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeAllTokens invalidates every access and refresh token issued to the
+// user so far. Access tokens carry iat in whole seconds, so the cutoff is
+// rounded up to the next second, which rejects tokens issued earlier in the
+// current one. RevokeAllTokens then waits for that second to begin, so
+// tokens issued once it returns are valid.
+func (us *InMemoryService) RevokeAllTokens(ctx context.Context, userID uuid.UUID) error {
+	cutoff := time.Now().Truncate(time.Second).Add(time.Second)
+	if err := us.revocations.RevokeAllForUser(ctx, userID, cutoff); err != nil {
+		return err
+	}
+	if err := us.refreshTokens.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	timer := time.NewTimer(time.Until(cutoff))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Introspect implements RFC 7662. A token is only active if it verifies, has
+// not been revoked and belongs to a user that exists, is activated and isn't
+// disabled. The hint only decides which token type is tried first; a token
+// of the other type is still recognized.
+func (us *InMemoryService) Introspect(ctx context.Context, token, tokenTypeHint string) (*Introspection, error) {
+	attempts := []func(context.Context, string) (*Introspection, error){us.introspectAccess, us.introspectRefresh}
+	if tokenTypeHint == "refresh_token" || !strings.Contains(token, ".") {
+		attempts = []func(context.Context, string) (*Introspection, error){us.introspectRefresh, us.introspectAccess}
+	}
+	for _, attempt := range attempts {
+		result, err := attempt(ctx, token)
+		if err != nil || result != nil {
+			return result, err
+		}
+	}
+	return &Introspection{Active: false}, nil
+}
+
+// introspectRefresh returns nil when token is not a refresh token this
+// service issued.
+func (us *InMemoryService) introspectRefresh(ctx context.Context, token string) (*Introspection, error) {
+	rt, err := us.refreshTokens.GetByHash(ctx, hashToken(token))
+	if errors.Is(err, ErrTokenNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	inactive := &Introspection{Active: false}
+	if rt.Revoked() || rt.Expired(time.Now()) {
+		return inactive, nil
+	}
+	u, err := us.users.GetByID(ctx, rt.UserID)
+	if errors.Is(err, ErrNotFound) {
+		return inactive, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !u.Activated || u.Disabled {
+		return inactive, nil
+	}
+	return &Introspection{
+		Active:    true,
+		Subject:   u.ID.String(),
+		Username:  u.Name,
+		TokenType: "refresh_token",
+		Exp:       rt.ExpiresAt.Unix(),
+		Iat:       rt.IssuedAt.Unix(),
+		Iss:       tokenIssuer,
+	}, nil
+}
+
+// introspectAccess returns nil when token is not a valid access token.
+func (us *InMemoryService) introspectAccess(ctx context.Context, token string) (*Introspection, error) {
+	claims, u, err := us.verify(ctx, token)
+	if errors.Is(err, ErrInvalidToken) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !u.Activated {
+		return &Introspection{Active: false}, nil
+	}
+	return &Introspection{
+		Active:    true,
+		Subject:   claims.Subject,
+		Username:  u.Name,
+		Roles:     claims.Roles,
+		Scope:     strings.Join(claims.Roles, " "),
+		TokenType: "access_token",
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	}, nil
+}
+
+func (us *InMemoryService) revokeAccessToken(ctx context.Context, claims *jwtCustomClaims) error {
+	if claims.ID == "" {
+		return fmt.Errorf("token has no jti")
+	}
+	return us.revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+func (us *InMemoryService) GetUserByName(ctx context.Context, name string) (*User, error) {
+	u, err := us.users.GetByName(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 
-func (us *InMemoryService) GetUserByID(id uuid.UUID) (*User, error) {
-	u, err := us.users.GetByID(id)
+func (us *InMemoryService) GetUserByID(ctx context.Context, id uuid.UUID) (*User, error) {
+	u, err := us.users.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 
-func (us *InMemoryService) GetUserByEmail(email string) (*User, error) {
-	u, err := us.users.GetByEmail(email)
+func (us *InMemoryService) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	u, err := us.users.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
 	return u, nil
 }
 
-func (us *InMemoryService) CreateNewUser(name, email, password string) (*User, error) {
-	_, err := us.users.GetByEmail(email)
-	if err == nil {
-		return nil, fmt.Errorf("user already exists")
+func (us *InMemoryService) CreateNewUser(ctx context.Context, name, email, password string) (*User, error) {
+	user, err := us.addUser(ctx, name, email, password, false)
+	if err != nil {
+		return nil, err
+	}
+	// The account exists at this point; a failed mail can be retried
+	// through ResendVerification.
+	us.sendVerification(ctx, user)
+	return user, nil
+}
+
+// CreateActivatedUser creates a user that is activated from the start on
+// behalf of actorID. No verification mail is sent.
+func (us *InMemoryService) CreateActivatedUser(ctx context.Context, actorID uuid.UUID, name, email, password string) (*User, error) {
+	user, err := us.addUser(ctx, name, email, password, true)
+	if err != nil {
+		return nil, err
 	}
+	us.audit(audit.ActionUserActivated, user.ID, actorID)
+	return user, nil
+}
+
+func (us *InMemoryService) addUser(ctx context.Context, name, email, password string, activated bool) (*User, error) {
 	user, err := NewUser(name, email, password)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %v", err)
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	user.Activated = activated
+	_, err = us.users.GetByEmail(ctx, email)
+	if err == nil {
+		return nil, ErrDuplicateEmail
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
 	}
-	err = us.users.Add(user)
+	err = us.users.Add(ctx, user)
 	if err != nil {
-		return nil, fmt.Errorf("failed to createa a new user: %v", err)
+		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 	return user, nil
 }
@@ -1,14 +1,26 @@
 package user
 
 import (
+	"awesomeProject/internal/audit"
+	"awesomeProject/internal/mail"
+	"awesomeProject/internal/metrics"
+	"awesomeProject/internal/signing"
+	"awesomeProject/pkg/authmw"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestInMemoryService_Authenticate(t *testing.T) {
-	t.Setenv("SIGN_KEY", "secret")
 	validEmail := "valid@email.test"
 	invalidEmail := "invalid@email"
 	validPassword := "validPassword"
@@ -30,7 +42,6 @@ func TestInMemoryService_Authenticate(t *testing.T) {
 		name    string
 		fields  fields
 		args    args
-		want    string
 		wantErr bool
 	}{
 		{name: "valid user",
@@ -42,7 +53,6 @@ func TestInMemoryService_Authenticate(t *testing.T) {
 				},
 			},
 			args:    args{email: validEmail, password: validPassword},
-			want:    "token",
 			wantErr: false,
 		},
 		{name: "invalid user",
@@ -54,7 +64,6 @@ func TestInMemoryService_Authenticate(t *testing.T) {
 				},
 			},
 			args:    args{email: validEmail, password: invalidPassword},
-			want:    "",
 			wantErr: true,
 		},
 		{
@@ -67,7 +76,6 @@ func TestInMemoryService_Authenticate(t *testing.T) {
 				},
 			},
 			args:    args{email: invalidEmail, password: validPassword},
-			want:    "",
 			wantErr: true,
 		},
 		{
@@ -80,7 +88,6 @@ func TestInMemoryService_Authenticate(t *testing.T) {
 				},
 			},
 			args:    args{email: "", password: validPassword},
-			want:    "",
 			wantErr: true,
 		},
 		{
@@ -93,22 +100,65 @@ func TestInMemoryService_Authenticate(t *testing.T) {
 				},
 			},
 			args:    args{email: validEmail, password: ""},
-			want:    "",
 			wantErr: true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			us := &InMemoryService{
-				users: tt.fields.users,
-			}
-			got, err := us.Authenticate(tt.args.email, tt.args.password)
+			us := newService(t, tt.fields.users)
+			got, err := us.Authenticate(t.Context(), tt.args.email, tt.args.password)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			if (got == "") != tt.wantErr {
-				t.Errorf("Authenticate() got = %v, want %v", got, tt.want)
+			if (got == nil) != tt.wantErr {
+				t.Errorf("Authenticate() got = %v, wantErr %v", got, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && (got.Token == "" || got.RefreshToken == "") {
+				t.Errorf("Authenticate() got = %v, want access and refresh tokens", got)
+			}
+		})
+	}
+}
+
+func TestInMemoryService_AuthenticateMetrics(t *testing.T) {
+	store := NewInMemStore()
+	admin, err := store.GetByName(t.Context(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Activate(t.Context(), admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	us := newService(t, store, WithRequireActivation(true), WithRolesURL(""))
+
+	count := func(outcome string) float64 {
+		return testutil.ToFloat64(metrics.Authentications.WithLabelValues(outcome))
+	}
+	tests := []struct {
+		identifier, password string
+		outcome              string
+	}{
+		{identifier: "admin", password: "password", outcome: metrics.AuthSuccess},
+		{identifier: "admin", password: "wrongPassword", outcome: metrics.AuthBadPassword},
+		{identifier: "nobody", password: "password", outcome: metrics.AuthUnknownUser},
+		{identifier: "testuser", password: "anotherPassword", outcome: metrics.AuthInactive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.outcome, func(t *testing.T) {
+			before := count(tt.outcome)
+			issued := testutil.ToFloat64(metrics.TokensIssued.WithLabelValues("access"))
+			_, _ = us.Authenticate(t.Context(), tt.identifier, tt.password)
+			if got := count(tt.outcome) - before; got != 1 {
+				t.Errorf("%s counter increased by %v, want 1", tt.outcome, got)
+			}
+			wantIssued := 0.0
+			if tt.outcome == metrics.AuthSuccess {
+				wantIssued = 1
+			}
+			if got := testutil.ToFloat64(metrics.TokensIssued.WithLabelValues("access")) - issued; got != wantIssued {
+				t.Errorf("access tokens issued increased by %v, want %v", got, wantIssued)
 			}
 		})
 	}
@@ -252,10 +302,8 @@ func TestInMemoryService_CreateNewUser(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			us := &InMemoryService{
-				users: tt.fields.users,
-			}
-			got, err := us.CreateNewUser(tt.args.name, tt.args.email, tt.args.password)
+			us := newService(t, tt.fields.users, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+			got, err := us.CreateNewUser(t.Context(), tt.args.name, tt.args.email, tt.args.password)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateNewUser() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -364,7 +412,7 @@ func TestInMemoryService_GetUserByEmail(t *testing.T) {
 			us := &InMemoryService{
 				users: tt.fields.users,
 			}
-			got, err := us.GetUserByEmail(tt.args.email)
+			got, err := us.GetUserByEmail(t.Context(), tt.args.email)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetUserByEmail() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -451,7 +499,7 @@ func TestInMemoryService_GetUserByID(t *testing.T) {
 			us := &InMemoryService{
 				users: tt.fields.users,
 			}
-			got, err := us.GetUserByID(tt.args.id)
+			got, err := us.GetUserByID(t.Context(), tt.args.id)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetUserByID() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -555,7 +603,7 @@ func TestInMemoryService_GetUserByName(t *testing.T) {
 			us := &InMemoryService{
 				users: tt.fields.users,
 			}
-			got, err := us.GetUserByName(tt.args.name)
+			got, err := us.GetUserByName(t.Context(), tt.args.name)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetUserByName() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -567,13 +615,16 @@ func TestInMemoryService_GetUserByName(t *testing.T) {
 }
 
 func TestNewInMemoryUserService(t *testing.T) {
+	keys := testKeys("secret")
 	type args struct {
 		users Store
+		opts  []Option
 	}
 	tests := []struct {
-		name string
-		args args
-		want *InMemoryService
+		name    string
+		args    args
+		want    *InMemoryService
+		wantErr bool
 	}{
 		{
 			name: "valid users",
@@ -583,6 +634,7 @@ func TestNewInMemoryUserService(t *testing.T) {
 					usersByID:    map[uuid.UUID]*User{},
 					usersByEmail: map[string]*User{},
 				},
+				opts: []Option{WithSigningKeys(keys)},
 			},
 			want: &InMemoryService{
 				users: &InMemStore{
@@ -590,87 +642,556 @@ func TestNewInMemoryUserService(t *testing.T) {
 					usersByID:    map[uuid.UUID]*User{},
 					usersByEmail: map[string]*User{},
 				},
+				refreshTokens: NewInMemRefreshStore(),
+				keys:          keys,
+				revocations:   NewInMemRevocationStore(),
+				actionTokens:  NewInMemActionTokenStore(),
+				mailer:        mail.NewLogSender(slog.Default()),
+				auditor:       audit.NewLogRecorder(slog.Default()),
+				rolesURL:      defaultRolesURL,
+				logger:        slog.Default(),
 			},
 		},
 		{
 			name: "nil users",
 			args: args{
 				users: nil,
+				opts:  []Option{WithSigningKeys(keys)},
 			},
 			want: &InMemoryService{
-				users: nil,
+				users:         nil,
+				refreshTokens: NewInMemRefreshStore(),
+				keys:          keys,
+				revocations:   NewInMemRevocationStore(),
+				actionTokens:  NewInMemActionTokenStore(),
+				mailer:        mail.NewLogSender(slog.Default()),
+				auditor:       audit.NewLogRecorder(slog.Default()),
+				rolesURL:      defaultRolesURL,
+				logger:        slog.Default(),
 			},
 		},
+		{
+			name:    "no signing keys",
+			args:    args{users: NewInMemStore()},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := NewInMemoryUserService(tt.args.users); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("NewInMemoryUserService() = %v, want %v", got, tt.want)
+			got, err := NewInMemoryUserService(tt.args.users, tt.args.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewInMemoryUserService() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != nil {
+				if cap(got.mailSlots) != maxPendingMails {
+					t.Errorf("NewInMemoryUserService() allows %d pending mails, want %d", cap(got.mailSlots), maxPendingMails)
+				}
+				got.mailSlots = nil
 			}
-			if got := NewInMemoryUserService(tt.args.users); !reflect.DeepEqual(got, tt.want) {
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("NewInMemoryUserService() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+// failingKeys is a KeySource without a usable key.
+type failingKeys struct{}
+
+func (failingKeys) SigningKey() (*signing.Key, error) {
+	return nil, errors.New("no key")
+}
+
+func (failingKeys) VerificationKey(string) (*signing.Key, error) {
+	return nil, errors.New("no key")
+}
+
+func (failingKeys) PublicKeys() ([]*signing.Key, error) {
+	return nil, errors.New("no key")
+}
+
 func Test_issueSignedToken(t *testing.T) {
 	type args struct {
+		keys signing.KeySource
 		user *User
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    string
 		wantErr bool
-		setEnv  bool
 	}{
 		{
 			name: "valid user",
 			args: args{
+				keys: testKeys("not empty"),
 				user: &User{
 					ID:   uuid.New(),
 					Name: "valid",
 				},
 			},
-			want:    "not empty	",
 			wantErr: false,
-			setEnv:  true,
 		},
 		{
 			name: "nil user",
 			args: args{
+				keys: testKeys("not empty"),
 				user: nil,
 			},
-			want:    "",
 			wantErr: true,
-			setEnv:  true,
 		},
 		{
-			name: "no secret",
+			name: "no key",
 			args: args{
+				keys: failingKeys{},
 				user: &User{
 					ID:   uuid.New(),
 					Name: "valid",
 				},
 			},
-			want:    "",
 			wantErr: true,
-			setEnv:  false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.setEnv {
-				t.Setenv("SIGN_KEY", "not empty")
-			}
-			got, err := issueSignedToken(tt.args.user, []string{})
+			got, err := issueSignedToken(tt.args.keys, tt.args.user, []string{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("issueSignedToken() error = %v, wantErr %v", err, tt.wantErr)
 			}
 			if len(got) == 0 && !tt.wantErr {
-				t.Errorf("issueSignedToken() got = %v, want %v", got, tt.want)
+				t.Errorf("issueSignedToken() returned an empty token")
+			}
+		})
+	}
+}
+
+func TestInMemoryService_Refresh(t *testing.T) {
+	validEmail := "valid@email.test"
+	validPassword := "validPassword"
+
+	validUser, err := NewUser("valid", validEmail, validPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &InMemStore{
+		usersByID:    map[uuid.UUID]*User{validUser.ID: validUser},
+		usersByEmail: map[string]*User{validEmail: validUser},
+	}
+
+	t.Run("rotates refresh token", func(t *testing.T) {
+		us := newService(t, users)
+		first, err := us.Authenticate(t.Context(), validEmail, validPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := us.Refresh(t.Context(), first.RefreshToken)
+		if err != nil {
+			t.Fatalf("Refresh() error = %v", err)
+		}
+		if second.Token == "" || second.RefreshToken == "" {
+			t.Errorf("Refresh() got = %v, want access and refresh tokens", second)
+		}
+		if second.RefreshToken == first.RefreshToken {
+			t.Errorf("Refresh() did not rotate the refresh token")
+		}
+	})
+
+	t.Run("reuse revokes the family", func(t *testing.T) {
+		us := newService(t, users)
+		first, err := us.Authenticate(t.Context(), validEmail, validPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := us.Refresh(t.Context(), first.RefreshToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := us.Refresh(t.Context(), first.RefreshToken); err == nil {
+			t.Fatalf("Refresh() with a rotated token should fail")
+		}
+		if _, err := us.Refresh(t.Context(), second.RefreshToken); err == nil {
+			t.Errorf("Refresh() with a token from a revoked family should fail")
+		}
+	})
+
+	t.Run("other logins are unaffected by reuse", func(t *testing.T) {
+		us := newService(t, users)
+		first, err := us.Authenticate(t.Context(), validEmail, validPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := us.Authenticate(t.Context(), validEmail, validPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := us.Refresh(t.Context(), first.RefreshToken); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := us.Refresh(t.Context(), first.RefreshToken); err == nil {
+			t.Fatalf("Refresh() with a rotated token should fail")
+		}
+		if _, err := us.Refresh(t.Context(), other.RefreshToken); err != nil {
+			t.Errorf("Refresh() error = %v, other session should remain valid", err)
+		}
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		us := newService(t, users)
+		if _, err := us.Refresh(t.Context(), "unknown"); err == nil {
+			t.Errorf("Refresh() with an unknown token should fail")
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		us := newService(t, users)
+		rt, value, err := newRefreshToken(validUser.ID, uuid.New())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rt.ExpiresAt = time.Now().Add(-time.Minute)
+		if err := us.refreshTokens.Add(t.Context(), rt); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := us.Refresh(t.Context(), value); err == nil {
+			t.Errorf("Refresh() with an expired token should fail")
+		}
+	})
+}
+
+func Test_issueSignedToken_AsymmetricKey(t *testing.T) {
+	key, err := signing.GenerateKey(signing.ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := signing.NewStaticKeys(key)
+	u := &User{ID: uuid.New(), Name: "valid"}
+
+	got, err := issueSignedToken(keys, u, []string{"admin"})
+	if err != nil {
+		t.Fatalf("issueSignedToken() error = %v", err)
+	}
+
+	var claims jwtCustomClaims
+	token, err := jwt.ParseWithClaims(got, &claims, signing.Keyfunc(keys))
+	if err != nil {
+		t.Fatalf("issued token does not verify: %v", err)
+	}
+	if token.Header["kid"] != key.ID {
+		t.Errorf("kid = %v, want %v", token.Header["kid"], key.ID)
+	}
+	if claims.Subject != u.ID.String() || !reflect.DeepEqual(claims.Roles, []string{"admin"}) {
+		t.Errorf("claims = %+v, want subject %s and roles [admin]", claims, u.ID)
+	}
+}
+
+// testKeys signs with the HS256 secret.
+func testKeys(secret string) signing.KeySource {
+	return signing.NewStaticKeys(signing.NewHMACKey("", []byte(secret)))
+}
+
+// newService builds a service that signs with testKeys("secret") unless opts
+// say otherwise.
+func newService(t *testing.T, users Store, opts ...Option) *InMemoryService {
+	t.Helper()
+	us, err := NewInMemoryUserService(users, append([]Option{WithSigningKeys(testKeys("secret"))}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return us
+}
+
+func newTestUserService(t *testing.T) (*InMemoryService, *User, string) {
+	t.Helper()
+	password := "validPassword"
+	u, err := NewUser("valid", "valid@email.test", password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := &InMemStore{
+		usersByID:    map[uuid.UUID]*User{u.ID: u},
+		usersByEmail: map[string]*User{u.Email: u},
+		usersByName:  map[string]*User{u.Name: u},
+	}
+	return newService(t, users), u, password
+}
+
+func TestInMemoryService_Verify(t *testing.T) {
+	us, u, password := newTestUserService(t)
+	tw, err := us.Authenticate(t.Context(), u.Email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := us.Verify(t.Context(), tw.Token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims.ID == "" {
+		t.Errorf("Verify() claims have no jti")
+	}
+	if claims.Subject != u.ID.String() {
+		t.Errorf("Verify() sub = %v, want %v", claims.Subject, u.ID)
+	}
+
+	if _, err := us.Verify(t.Context(), "not a token"); err == nil {
+		t.Errorf("Verify() should reject a malformed token")
+	}
+
+	us.keys = testKeys("another secret")
+	if _, err := us.Verify(t.Context(), tw.Token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() of a token with a bad signature error = %v, want ErrInvalidToken", err)
+	}
+
+	// A key store outage must not look like an invalid token.
+	us.keys = unavailableKeys{}
+	_, err = us.Verify(t.Context(), tw.Token)
+	var storeErr *StoreError
+	if !errors.As(err, &storeErr) || !errors.Is(err, authmw.ErrUnavailable) || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Verify() with the key store down error = %v, want a StoreError matching authmw.ErrUnavailable", err)
+	}
+}
+
+// unavailableKeys is a KeySource whose store can't be reached.
+type unavailableKeys struct {
+	failingKeys
+}
+
+func (unavailableKeys) VerificationKey(string) (*signing.Key, error) {
+	return nil, fmt.Errorf("%w: connection refused", signing.ErrKeysUnavailable)
+}
+
+func TestInMemoryService_Logout(t *testing.T) {
+	us, u, password := newTestUserService(t)
+	tw, err := us.Authenticate(t.Context(), u.Email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := us.Authenticate(t.Context(), u.Email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.Logout(t.Context(), tw.Token, tw.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if _, err := us.Verify(t.Context(), tw.Token); err == nil {
+		t.Errorf("access token is still valid after Logout()")
+	}
+	if _, err := us.Refresh(t.Context(), tw.RefreshToken); err == nil {
+		t.Errorf("refresh token is still valid after Logout()")
+	}
+	if _, err := us.Verify(t.Context(), other.Token); err != nil {
+		t.Errorf("Logout() revoked another session: %v", err)
+	}
+	if err := us.Logout(t.Context(), tw.Token, ""); err == nil {
+		t.Errorf("Logout() with a revoked token should fail")
+	}
+}
+
+func TestInMemoryService_RevokeToken(t *testing.T) {
+	tests := []struct {
+		name  string
+		token func(tw *TokenWrapper) string
+		hint  string
+	}{
+		{name: "access token", token: func(tw *TokenWrapper) string { return tw.Token }, hint: ""},
+		{name: "access token with refresh hint", token: func(tw *TokenWrapper) string { return tw.Token }, hint: "refresh_token"},
+		{name: "refresh token", token: func(tw *TokenWrapper) string { return tw.RefreshToken }, hint: "refresh_token"},
+		{name: "refresh token without hint", token: func(tw *TokenWrapper) string { return tw.RefreshToken }, hint: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us, u, password := newTestUserService(t)
+			tw, err := us.Authenticate(t.Context(), u.Email, password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := us.RevokeToken(t.Context(), tt.token(tw), tt.hint); err != nil {
+				t.Fatalf("RevokeToken() error = %v", err)
+			}
+			if tt.token(tw) == tw.Token {
+				if _, err := us.Verify(t.Context(), tw.Token); err == nil {
+					t.Errorf("access token is still valid after RevokeToken()")
+				}
+			} else {
+				if _, err := us.Refresh(t.Context(), tw.RefreshToken); err == nil {
+					t.Errorf("refresh token is still valid after RevokeToken()")
+				}
 			}
 		})
 	}
+
+	t.Run("unknown token", func(t *testing.T) {
+		us, _, _ := newTestUserService(t)
+		if err := us.RevokeToken(t.Context(), "unknown", ""); err != nil {
+			t.Errorf("RevokeToken() of an unknown token should succeed, got %v", err)
+		}
+	})
+}
+
+func TestInMemoryService_RevokeAllTokens(t *testing.T) {
+	us, u, password := newTestUserService(t)
+	first, err := us.Authenticate(t.Context(), u.Email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := us.Authenticate(t.Context(), u.Email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := us.RevokeAllTokens(t.Context(), u.ID); err != nil {
+		t.Fatalf("RevokeAllTokens() error = %v", err)
+	}
+	for _, tw := range []*TokenWrapper{first, second} {
+		if _, err := us.Verify(t.Context(), tw.Token); err == nil {
+			t.Errorf("access token is still valid after RevokeAllTokens()")
+		}
+		if _, err := us.Refresh(t.Context(), tw.RefreshToken); err == nil {
+			t.Errorf("refresh token is still valid after RevokeAllTokens()")
+		}
+	}
+
+	t.Run("tokens issued in the same second", func(t *testing.T) {
+		us, u, password := newTestUserService(t)
+		// Start early in a second so the revoke below lands in the same one.
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+		tw, err := us.Authenticate(t.Context(), u.Email, password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := us.RevokeAllTokens(t.Context(), u.ID); err != nil {
+			t.Fatalf("RevokeAllTokens() error = %v", err)
+		}
+		if _, err := us.Verify(t.Context(), tw.Token); err == nil {
+			t.Errorf("access token issued in the same second is still valid after RevokeAllTokens()")
+		}
+	})
+
+	t.Run("tokens issued right after", func(t *testing.T) {
+		us, u, password := newTestUserService(t)
+		for range 3 {
+			if err := us.RevokeAllTokens(t.Context(), u.ID); err != nil {
+				t.Fatalf("RevokeAllTokens() error = %v", err)
+			}
+			tw, err := us.Authenticate(t.Context(), u.Email, password)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := us.Verify(t.Context(), tw.Token); err != nil {
+				t.Fatalf("Verify() error = %v for a token issued right after RevokeAllTokens()", err)
+			}
+		}
+	})
+}
+
+func TestInMemoryService_Introspect(t *testing.T) {
+	us, u, password := newTestUserService(t)
+	tw, err := us.Authenticate(t.Context(), u.Email, password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := us.Introspect(t.Context(), tw.Token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Active {
+		t.Errorf("Introspect() active = true for a user that is not activated")
+	}
+
+	u.Activate()
+	got, err = us.Introspect(t.Context(), tw.Token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active || got.Subject != u.ID.String() || got.Username != u.Name || got.TokenType != "access_token" {
+		t.Errorf("Introspect() = %+v, want an active access token for %s", got, u.ID)
+	}
+	if got.Exp == 0 || got.Jti == "" {
+		t.Errorf("Introspect() = %+v, want exp and jti", got)
+	}
+
+	got, err = us.Introspect(t.Context(), tw.RefreshToken, "refresh_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active || got.TokenType != "refresh_token" {
+		t.Errorf("Introspect() = %+v, want an active refresh token", got)
+	}
+
+	// A wrong hint must not make a valid token look inactive.
+	got, err = us.Introspect(t.Context(), tw.Token, "refresh_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active || got.TokenType != "access_token" {
+		t.Errorf("Introspect() with a refresh_token hint = %+v, want an active access token", got)
+	}
+	got, err = us.Introspect(t.Context(), tw.RefreshToken, "access_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active || got.TokenType != "refresh_token" {
+		t.Errorf("Introspect() with an access_token hint = %+v, want an active refresh token", got)
+	}
+
+	if err := us.RevokeToken(t.Context(), tw.Token, ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err = us.Introspect(t.Context(), tw.Token, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Active {
+		t.Errorf("Introspect() active = true for a revoked token")
+	}
+
+	got, err = us.Introspect(t.Context(), "garbage", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Active {
+		t.Errorf("Introspect() active = true for an unknown token")
+	}
+}
+
+// countingStore counts user lookups by ID.
+type countingStore struct {
+	*InMemStore
+	getByID int
+}
+
+func (c *countingStore) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
+	c.getByID++
+	return c.InMemStore.GetByID(ctx, id)
+}
+
+func TestInMemoryService_Introspect_LoadsUserOnce(t *testing.T) {
+	u, err := NewUser("valid", "valid@email.test", "validPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Activate()
+	users := &countingStore{InMemStore: &InMemStore{
+		usersByID:    map[uuid.UUID]*User{u.ID: u},
+		usersByEmail: map[string]*User{u.Email: u},
+		usersByName:  map[string]*User{u.Name: u},
+	}}
+	us := newService(t, users)
+	tw, err := us.Authenticate(t.Context(), u.Email, "validPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users.getByID = 0
+	got, err := us.Introspect(t.Context(), tw.Token, "access_token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Active {
+		t.Errorf("Introspect() = %+v, want an active access token", got)
+	}
+	if users.getByID != 1 {
+		t.Errorf("Introspect() looked the user up %d times, want 1", users.getByID)
+	}
 }
@@ -1,16 +1,39 @@
 package user
 
 import (
+	"cmp"
+	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Store interface {
-	GetByName(string) (*User, error)
-	GetByID(uuid.UUID) (*User, error)
-	GetByEmail(string) (*User, error)
-	Add(*User) error
+	GetByName(context.Context, string) (*User, error)
+	GetByID(context.Context, uuid.UUID) (*User, error)
+	GetByEmail(context.Context, string) (*User, error)
+	Add(context.Context, *User) error
+	Activate(context.Context, uuid.UUID) error
+	SetDisabled(ctx context.Context, id uuid.UUID, disabled bool) error
+	UpdatePassword(context.Context, uuid.UUID, []byte) error
+	// Update persists the name, email and activation state of an existing
+	// user.
+	Update(context.Context, *User) error
+	// Delete soft-deletes a user: the getters stop returning it, but it can
+	// be restored until it is purged.
+	Delete(context.Context, uuid.UUID) error
+	// Restore undoes a Delete made after deletedAfter.
+	Restore(ctx context.Context, id uuid.UUID, deletedAfter time.Time) error
+	// Purge permanently removes users deleted at or before deletedBefore and
+	// reports how many were removed.
+	Purge(ctx context.Context, deletedBefore time.Time) (int, error)
+	List(context.Context, ListQuery) (*ListPage, error)
+	// Search returns up to limit users whose name or email resembles query,
+	// best match first.
+	Search(ctx context.Context, query string, limit int) ([]SearchResult, error)
 }
 
 type InMemStore struct {
@@ -19,7 +42,19 @@ type InMemStore struct {
 	usersByEmail map[string]*User
 }
 
-func (r InMemStore) Add(u *User) error {
+func (r InMemStore) Add(_ context.Context, u *User) error {
+	return r.add(u)
+}
+
+func (r InMemStore) add(u *User) error {
+	// Like the unique constraints in Postgres, soft-deleted users keep their
+	// name and email until they are purged.
+	if other, ok := r.usersByName[u.Name]; ok && other.ID != u.ID {
+		return ErrDuplicateName
+	}
+	if other, ok := r.usersByEmail[u.Email]; ok && other.ID != u.ID {
+		return ErrDuplicateEmail
+	}
 	r.usersByID[u.ID] = u
 	r.usersByName[u.Name] = u
 	r.usersByEmail[u.Email] = u
@@ -45,7 +80,7 @@ func NewInMemStore() *InMemStore {
 		if err != nil {
 			panic(fmt.Sprintf("failed to create user %s: %v", userData.email, err))
 		}
-		if err := r.Add(u); err != nil {
+		if err := r.add(u); err != nil {
 			panic(fmt.Sprintf("failed to add user %s: %v", userData.email, err))
 		}
 	}
@@ -53,27 +88,229 @@ func NewInMemStore() *InMemStore {
 	return &r
 }
 
-func (r InMemStore) GetByName(name string) (*User, error) {
+func (r InMemStore) GetByName(_ context.Context, name string) (*User, error) {
 	user, ok := r.usersByName[name]
-	if !ok {
-		return nil, fmt.Errorf("user not found")
+	if !ok || user.DeletedAt != nil {
+		return nil, ErrNotFound
 	}
 	return user, nil
 }
 
-func (r InMemStore) GetByID(id uuid.UUID) (*User, error) {
+func (r InMemStore) GetByID(_ context.Context, id uuid.UUID) (*User, error) {
 	user, ok := r.usersByID[id]
-	if !ok {
-		return nil, fmt.Errorf("user not found")
+	if !ok || user.DeletedAt != nil {
+		return nil, ErrNotFound
 	}
 	return user, nil
 }
 
-func (r InMemStore) GetByEmail(email string) (*User, error) {
+func (r InMemStore) GetByEmail(_ context.Context, email string) (*User, error) {
 	user, ok := r.usersByEmail[email]
 
-	if !ok {
-		return nil, fmt.Errorf("user not found")
+	if !ok || user.DeletedAt != nil {
+		return nil, ErrNotFound
 	}
 	return user, nil
 }
+
+func (r InMemStore) Activate(_ context.Context, id uuid.UUID) error {
+	user, ok := r.usersByID[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrNotFound
+	}
+	user.Activate()
+	if u, ok := r.usersByName[user.Name]; ok && u.ID == id {
+		u.Activate()
+	}
+	if u, ok := r.usersByEmail[user.Email]; ok && u.ID == id {
+		u.Activate()
+	}
+	return nil
+}
+
+func (r InMemStore) SetDisabled(_ context.Context, id uuid.UUID, disabled bool) error {
+	user, ok := r.usersByID[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrNotFound
+	}
+	user.Disabled = disabled
+	if u, ok := r.usersByName[user.Name]; ok && u.ID == id {
+		u.Disabled = disabled
+	}
+	if u, ok := r.usersByEmail[user.Email]; ok && u.ID == id {
+		u.Disabled = disabled
+	}
+	return nil
+}
+
+func (r InMemStore) UpdatePassword(_ context.Context, id uuid.UUID, hash []byte) error {
+	user, ok := r.usersByID[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrNotFound
+	}
+	user.hash = hash
+	if u, ok := r.usersByName[user.Name]; ok && u.ID == id {
+		u.hash = hash
+	}
+	if u, ok := r.usersByEmail[user.Email]; ok && u.ID == id {
+		u.hash = hash
+	}
+	return nil
+}
+
+func (r InMemStore) Update(_ context.Context, u *User) error {
+	old, ok := r.usersByID[u.ID]
+	if !ok || old.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if other, ok := r.usersByName[u.Name]; ok && other.ID != u.ID {
+		return ErrDuplicateName
+	}
+	if other, ok := r.usersByEmail[u.Email]; ok && other.ID != u.ID {
+		return ErrDuplicateEmail
+	}
+	updated := *old
+	updated.Name = u.Name
+	updated.Email = u.Email
+	updated.Activated = u.Activated
+	delete(r.usersByName, old.Name)
+	delete(r.usersByEmail, old.Email)
+	return r.add(&updated)
+}
+
+func (r InMemStore) Delete(_ context.Context, id uuid.UUID) error {
+	user, ok := r.usersByID[id]
+	if !ok || user.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	r.setDeletedAt(user, &now)
+	return nil
+}
+
+func (r InMemStore) Restore(_ context.Context, id uuid.UUID, deletedAfter time.Time) error {
+	user, ok := r.usersByID[id]
+	if !ok || user.DeletedAt == nil || !user.DeletedAt.After(deletedAfter) {
+		return ErrNotFound
+	}
+	r.setDeletedAt(user, nil)
+	return nil
+}
+
+func (r InMemStore) Purge(_ context.Context, deletedBefore time.Time) (int, error) {
+	purged := 0
+	for id, user := range r.usersByID {
+		if user.DeletedAt == nil || user.DeletedAt.After(deletedBefore) {
+			continue
+		}
+		delete(r.usersByID, id)
+		delete(r.usersByName, user.Name)
+		delete(r.usersByEmail, user.Email)
+		purged++
+	}
+	return purged, nil
+}
+
+func (r InMemStore) setDeletedAt(user *User, at *time.Time) {
+	user.DeletedAt = at
+	if u, ok := r.usersByName[user.Name]; ok && u.ID == user.ID {
+		u.DeletedAt = at
+	}
+	if u, ok := r.usersByEmail[user.Email]; ok && u.ID == user.ID {
+		u.DeletedAt = at
+	}
+}
+
+func (r InMemStore) List(_ context.Context, q ListQuery) (*ListPage, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+	users := make([]*User, 0, len(r.usersByID))
+	for _, u := range r.usersByID {
+		if u.DeletedAt == nil && q.Filter.matches(u) {
+			users = append(users, u)
+		}
+	}
+	slices.SortFunc(users, func(a, b *User) int {
+		c := compareKeyset(a.Joined, a.ID, b.Joined, b.ID)
+		if q.Order == SortDesc {
+			return -c
+		}
+		return c
+	})
+
+	page := &ListPage{Users: []*User{}}
+	if q.WithTotal {
+		total := len(users)
+		page.Total = &total
+	}
+	for _, u := range users {
+		if q.After != nil {
+			c := compareKeyset(u.Joined, u.ID, q.After.Joined, q.After.ID)
+			if (q.Order == SortDesc && c >= 0) || (q.Order != SortDesc && c <= 0) {
+				continue
+			}
+		}
+		if len(page.Users) == q.Limit {
+			page.Next = cursorOf(page.Users[len(page.Users)-1])
+			break
+		}
+		page.Users = append(page.Users, u)
+	}
+	return page, nil
+}
+
+func (f ListFilter) matches(u *User) bool {
+	if f.Activated != nil && u.Activated != *f.Activated {
+		return false
+	}
+	if f.JoinedAfter != nil && u.Joined.Before(*f.JoinedAfter) {
+		return false
+	}
+	if f.JoinedBefore != nil && !u.Joined.Before(*f.JoinedBefore) {
+		return false
+	}
+	return strings.HasPrefix(u.Name, f.NamePrefix) && strings.HasPrefix(u.Email, f.EmailPrefix)
+}
+
+func compareKeyset(aJoined time.Time, aID uuid.UUID, bJoined time.Time, bID uuid.UUID) int {
+	if c := aJoined.Compare(bJoined); c != 0 {
+		return c
+	}
+	return strings.Compare(aID.String(), bID.String())
+}
+
+// Search is a stand-in for the trigram search of PostgresStore: it matches
+// case-insensitive substrings and scores them by how much of the field they
+// cover.
+func (r InMemStore) Search(_ context.Context, query string, limit int) ([]SearchResult, error) {
+	query = strings.ToLower(query)
+	results := []SearchResult{}
+	for _, u := range r.usersByID {
+		if u.DeletedAt != nil {
+			continue
+		}
+		score := max(substringScore(query, u.Name), substringScore(query, u.Email))
+		if score > 0 {
+			results = append(results, SearchResult{User: u, Score: score})
+		}
+	}
+	slices.SortFunc(results, func(a, b SearchResult) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return strings.Compare(a.User.ID.String(), b.User.ID.String())
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func substringScore(query, field string) float64 {
+	field = strings.ToLower(field)
+	if query == "" || !strings.Contains(field, query) {
+		return 0
+	}
+	return float64(len(query)) / float64(len(field))
+}
@@ -1,7 +1,11 @@
 package user
 
 import (
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 func TestNewInMemStore(t *testing.T) {
@@ -22,3 +26,160 @@ func TestNewInMemStore(t *testing.T) {
 		})
 	}
 }
+
+func TestInMemStore_Activate(t *testing.T) {
+	s := NewInMemStore()
+	u, err := s.GetByName(t.Context(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Activate(t.Context(), u.ID); err != nil {
+		t.Fatalf("Activate() error = %v", err)
+	}
+	byEmail, _ := s.GetByEmail(t.Context(), u.Email)
+	if !byEmail.Activated {
+		t.Errorf("Activate() did not activate the user")
+	}
+	if err := s.Activate(t.Context(), uuid.New()); err == nil {
+		t.Errorf("Activate() of an unknown user should fail")
+	}
+}
+
+func TestInMemStore_UpdatePassword(t *testing.T) {
+	s := NewInMemStore()
+	u, err := s.GetByName(t.Context(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := *u
+	if err := updated.SetPassword("newpassword"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdatePassword(t.Context(), u.ID, updated.hash); err != nil {
+		t.Fatalf("UpdatePassword() error = %v", err)
+	}
+	byEmail, _ := s.GetByEmail(t.Context(), u.Email)
+	if !byEmail.CheckPassword("newpassword") {
+		t.Errorf("UpdatePassword() did not change the password")
+	}
+	if err := s.UpdatePassword(t.Context(), uuid.New(), updated.hash); err == nil {
+		t.Errorf("UpdatePassword() of an unknown user should fail")
+	}
+}
+
+func TestInMemStore_Update(t *testing.T) {
+	s := NewInMemStore()
+	u, err := s.GetByName(t.Context(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taken := *u
+	taken.Name = "testuser"
+	if err := s.Update(t.Context(), &taken); !errors.Is(err, ErrDuplicateName) {
+		t.Errorf("Update() to a taken name error = %v, want %v", err, ErrDuplicateName)
+	}
+
+	updated := *u
+	updated.Name = "root"
+	updated.Email = "root@example.com"
+	if err := s.Update(t.Context(), &updated); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if _, err := s.GetByName(t.Context(), "admin"); err == nil {
+		t.Errorf("Update() left the old name indexed")
+	}
+	if _, err := s.GetByEmail(t.Context(), "admin@example.com"); err == nil {
+		t.Errorf("Update() left the old email indexed")
+	}
+	byEmail, err := s.GetByEmail(t.Context(), "root@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byEmail.ID != u.ID || byEmail.Name != "root" || !byEmail.CheckPassword("password") {
+		t.Errorf("Update() stored %+v", byEmail)
+	}
+}
+
+func TestInMemStore_Delete(t *testing.T) {
+	s := NewInMemStore()
+	u, err := s.GetByName(t.Context(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(t.Context(), u.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.GetByID(t.Context(), u.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetByID() error = %v for a deleted user, want %v", err, ErrNotFound)
+	}
+	if _, err := s.GetByEmail(t.Context(), u.Email); err == nil {
+		t.Errorf("GetByEmail() returned a deleted user")
+	}
+	if err := s.Delete(t.Context(), u.ID); err == nil {
+		t.Errorf("Delete() of a deleted user should fail")
+	}
+	reuse, err := NewUser("admin", "other@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), reuse); err == nil {
+		t.Errorf("Add() reused the name of a user that wasn't purged yet")
+	}
+}
+
+func TestInMemStore_Restore(t *testing.T) {
+	s := NewInMemStore()
+	u, err := s.GetByName(t.Context(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(t.Context(), u.ID, time.Now().Add(-time.Hour)); err == nil {
+		t.Errorf("Restore() of a user that isn't deleted should fail")
+	}
+	if err := s.Delete(t.Context(), u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Restore(t.Context(), u.ID, time.Now().Add(time.Hour)); err == nil {
+		t.Errorf("Restore() after the grace period should fail")
+	}
+	if err := s.Restore(t.Context(), u.ID, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := s.GetByEmail(t.Context(), u.Email); err != nil {
+		t.Errorf("GetByEmail() error = %v after Restore()", err)
+	}
+}
+
+func TestInMemStore_Purge(t *testing.T) {
+	s := NewInMemStore()
+	u, err := s.GetByName(t.Context(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(t.Context(), u.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.Purge(t.Context(), time.Now().Add(-time.Hour))
+	if err != nil || n != 0 {
+		t.Errorf("Purge() = %d, %v, want nothing purged inside the grace period", n, err)
+	}
+	n, err = s.Purge(t.Context(), time.Now())
+	if err != nil || n != 1 {
+		t.Fatalf("Purge() = %d, %v, want 1", n, err)
+	}
+	if err := s.Restore(t.Context(), u.ID, time.Time{}); err == nil {
+		t.Errorf("Restore() of a purged user should fail")
+	}
+	if _, err := s.GetByName(t.Context(), "testuser"); err != nil {
+		t.Errorf("Purge() removed a user that wasn't deleted")
+	}
+	reuse, err := NewUser("admin", "admin@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(t.Context(), reuse); err != nil {
+		t.Errorf("Add() error = %v reusing the name of a purged user", err)
+	}
+}
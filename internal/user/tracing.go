@@ -0,0 +1,192 @@
+package user
+
+import (
+	"awesomeProject/internal/telemetry"
+	"awesomeProject/pkg/authmw"
+	"context"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedService records a span for every Service call. Names, emails,
+// passwords and tokens are never put on spans; user IDs are.
+type TracedService struct {
+	service Service
+}
+
+func NewTracedService(service Service) *TracedService {
+	return &TracedService{service: service}
+}
+
+func (s *TracedService) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return telemetry.Tracer().Start(ctx, "user.Service/"+method, trace.WithAttributes(attrs...))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func userIDAttr(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("user.id", id.String())
+}
+
+func actorIDAttr(id uuid.UUID) attribute.KeyValue {
+	return attribute.String("user.actor_id", id.String())
+}
+
+func (s *TracedService) GetUserByName(ctx context.Context, name string) (u *User, err error) {
+	ctx, span := s.start(ctx, "GetUserByName")
+	defer func() { endSpan(span, err) }()
+	return s.service.GetUserByName(ctx, name)
+}
+
+func (s *TracedService) GetUserByID(ctx context.Context, id uuid.UUID) (u *User, err error) {
+	ctx, span := s.start(ctx, "GetUserByID", userIDAttr(id))
+	defer func() { endSpan(span, err) }()
+	return s.service.GetUserByID(ctx, id)
+}
+
+func (s *TracedService) GetUserByEmail(ctx context.Context, email string) (u *User, err error) {
+	ctx, span := s.start(ctx, "GetUserByEmail")
+	defer func() { endSpan(span, err) }()
+	return s.service.GetUserByEmail(ctx, email)
+}
+
+func (s *TracedService) CreateNewUser(ctx context.Context, name, email, password string) (u *User, err error) {
+	ctx, span := s.start(ctx, "CreateNewUser")
+	defer func() { endSpan(span, err) }()
+	return s.service.CreateNewUser(ctx, name, email, password)
+}
+
+func (s *TracedService) CreateActivatedUser(ctx context.Context, actorID uuid.UUID, name, email, password string) (u *User, err error) {
+	ctx, span := s.start(ctx, "CreateActivatedUser")
+	defer func() { endSpan(span, err) }()
+	return s.service.CreateActivatedUser(ctx, actorID, name, email, password)
+}
+
+func (s *TracedService) Authenticate(ctx context.Context, email, password string) (tw *TokenWrapper, err error) {
+	ctx, span := s.start(ctx, "Authenticate")
+	defer func() { endSpan(span, err) }()
+	return s.service.Authenticate(ctx, email, password)
+}
+
+func (s *TracedService) Refresh(ctx context.Context, refreshToken string) (tw *TokenWrapper, err error) {
+	ctx, span := s.start(ctx, "Refresh")
+	defer func() { endSpan(span, err) }()
+	return s.service.Refresh(ctx, refreshToken)
+}
+
+func (s *TracedService) Logout(ctx context.Context, accessToken, refreshToken string) (err error) {
+	ctx, span := s.start(ctx, "Logout")
+	defer func() { endSpan(span, err) }()
+	return s.service.Logout(ctx, accessToken, refreshToken)
+}
+
+func (s *TracedService) RevokeToken(ctx context.Context, token, tokenTypeHint string) (err error) {
+	ctx, span := s.start(ctx, "RevokeToken")
+	defer func() { endSpan(span, err) }()
+	return s.service.RevokeToken(ctx, token, tokenTypeHint)
+}
+
+func (s *TracedService) RevokeAllTokens(ctx context.Context, userID uuid.UUID) (err error) {
+	ctx, span := s.start(ctx, "RevokeAllTokens", userIDAttr(userID))
+	defer func() { endSpan(span, err) }()
+	return s.service.RevokeAllTokens(ctx, userID)
+}
+
+func (s *TracedService) Introspect(ctx context.Context, token, tokenTypeHint string) (i *Introspection, err error) {
+	ctx, span := s.start(ctx, "Introspect")
+	defer func() { endSpan(span, err) }()
+	return s.service.Introspect(ctx, token, tokenTypeHint)
+}
+
+func (s *TracedService) Verify(ctx context.Context, token string) (c *authmw.Claims, err error) {
+	ctx, span := s.start(ctx, "Verify")
+	defer func() { endSpan(span, err) }()
+	return s.service.Verify(ctx, token)
+}
+
+func (s *TracedService) VerifyEmail(ctx context.Context, token string) (err error) {
+	ctx, span := s.start(ctx, "VerifyEmail")
+	defer func() { endSpan(span, err) }()
+	return s.service.VerifyEmail(ctx, token)
+}
+
+func (s *TracedService) ResendVerification(ctx context.Context, email string) (err error) {
+	ctx, span := s.start(ctx, "ResendVerification")
+	defer func() { endSpan(span, err) }()
+	return s.service.ResendVerification(ctx, email)
+}
+
+func (s *TracedService) ForgotPassword(ctx context.Context, email string) (err error) {
+	ctx, span := s.start(ctx, "ForgotPassword")
+	defer func() { endSpan(span, err) }()
+	return s.service.ForgotPassword(ctx, email)
+}
+
+func (s *TracedService) ResetPassword(ctx context.Context, token, password string) (err error) {
+	ctx, span := s.start(ctx, "ResetPassword")
+	defer func() { endSpan(span, err) }()
+	return s.service.ResetPassword(ctx, token, password)
+}
+
+func (s *TracedService) ChangePassword(ctx context.Context, id uuid.UUID, current, password string) (err error) {
+	ctx, span := s.start(ctx, "ChangePassword", userIDAttr(id))
+	defer func() { endSpan(span, err) }()
+	return s.service.ChangePassword(ctx, id, current, password)
+}
+
+func (s *TracedService) UpdateUser(ctx context.Context, actorID, id uuid.UUID, update UpdateDTO) (u *User, err error) {
+	ctx, span := s.start(ctx, "UpdateUser", actorIDAttr(actorID), userIDAttr(id))
+	defer func() { endSpan(span, err) }()
+	return s.service.UpdateUser(ctx, actorID, id, update)
+}
+
+func (s *TracedService) DeleteUser(ctx context.Context, actorID, id uuid.UUID) (err error) {
+	ctx, span := s.start(ctx, "DeleteUser", actorIDAttr(actorID), userIDAttr(id))
+	defer func() { endSpan(span, err) }()
+	return s.service.DeleteUser(ctx, actorID, id)
+}
+
+func (s *TracedService) RestoreUser(ctx context.Context, actorID, id uuid.UUID) (err error) {
+	ctx, span := s.start(ctx, "RestoreUser", actorIDAttr(actorID), userIDAttr(id))
+	defer func() { endSpan(span, err) }()
+	return s.service.RestoreUser(ctx, actorID, id)
+}
+
+func (s *TracedService) ListUsers(ctx context.Context, q ListQuery) (p *ListPage, err error) {
+	ctx, span := s.start(ctx, "ListUsers", attribute.Int("list.limit", q.Limit))
+	defer func() { endSpan(span, err) }()
+	return s.service.ListUsers(ctx, q)
+}
+
+func (s *TracedService) FuzzySearchUsers(ctx context.Context, query string, limit int) (r []SearchResult, err error) {
+	ctx, span := s.start(ctx, "FuzzySearchUsers", attribute.Int("search.limit", limit))
+	defer func() { endSpan(span, err) }()
+	return s.service.FuzzySearchUsers(ctx, query, limit)
+}
+
+func (s *TracedService) SetActivated(ctx context.Context, actorID, id uuid.UUID, activated bool) (err error) {
+	ctx, span := s.start(ctx, "SetActivated", actorIDAttr(actorID), userIDAttr(id), attribute.Bool("user.activated", activated))
+	defer func() { endSpan(span, err) }()
+	return s.service.SetActivated(ctx, actorID, id, activated)
+}
+
+func (s *TracedService) SetDisabled(ctx context.Context, actorID, id uuid.UUID, disabled bool) (err error) {
+	ctx, span := s.start(ctx, "SetDisabled", actorIDAttr(actorID), userIDAttr(id), attribute.Bool("user.disabled", disabled))
+	defer func() { endSpan(span, err) }()
+	return s.service.SetDisabled(ctx, actorID, id, disabled)
+}
+
+func (s *TracedService) SetPassword(ctx context.Context, actorID, id uuid.UUID, password string) (err error) {
+	ctx, span := s.start(ctx, "SetPassword", actorIDAttr(actorID), userIDAttr(id))
+	defer func() { endSpan(span, err) }()
+	return s.service.SetPassword(ctx, actorID, id, password)
+}
@@ -0,0 +1,58 @@
+package user
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedService(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	s := NewTracedService(newService(t, NewInMemStore(), WithRolesURL("")))
+	admin, err := s.GetUserByName(t.Context(), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Authenticate(t.Context(), "admin", "wrongPassword"); err == nil {
+		t.Fatal("Authenticate() with a wrong password should fail")
+	}
+	ctx, parent := otel.Tracer("test").Start(t.Context(), "request")
+	if _, err := s.GetUserByID(ctx, admin.ID); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("recorded %d spans, want 4", len(spans))
+	}
+	if spans[2].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("GetUserByID span is not a child of the caller's span")
+	}
+	if spans[1].Name() != "user.Service/Authenticate" || spans[1].Status().Code != codes.Error {
+		t.Errorf("span = %s %v, want a failed Authenticate span", spans[1].Name(), spans[1].Status())
+	}
+	found := false
+	for _, attr := range spans[2].Attributes() {
+		if attr.Key == "user.id" && attr.Value.AsString() == admin.ID.String() {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("GetUserByID span attributes = %v, want user.id", spans[2].Attributes())
+	}
+	for _, span := range spans {
+		for _, attr := range span.Attributes() {
+			if attr.Value.AsString() == "admin" || attr.Value.AsString() == "wrongPassword" {
+				t.Errorf("span %s carries %s = %q", span.Name(), attr.Key, attr.Value.AsString())
+			}
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package user
 
 import (
-	"fmt"
+	"awesomeProject/internal/metrics"
 	"strings"
 	"time"
 
@@ -18,17 +18,22 @@ type User struct {
 	hash      []byte
 	Joined    time.Time
 	Activated bool
+	// Disabled is set by an operator and keeps the user from signing in
+	// until they are enabled again. Verifying the email doesn't clear it.
+	Disabled bool
+	// DeletedAt is set while the account is soft-deleted and can still be
+	// restored.
+	DeletedAt *time.Time
 }
 
+// NewUser validates the fields and hashes the password. Invalid fields are
+// reported together in a *ValidationError.
 func NewUser(name, email, password string) (*User, error) {
-	if !isValidEmail(email) {
-		return nil, fmt.Errorf("invalid email")
-	}
-	if !isPasswordValid(password) {
-		return nil, fmt.Errorf("invalid password")
+	if err := (CreationDTO{Name: name, Email: email, Password: password}).Validate(); err != nil {
+		return nil, err
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := hashPassword(password)
 	if err != nil {
 		return nil, err
 	}
@@ -68,17 +73,13 @@ func isValidEmail(email string) bool {
 	return true
 }
 
-func isPasswordValid(password string) bool {
-	if len(password) < 8 {
-		return false
-	}
-	if strings.Contains(password, " ") {
-		return false
-	}
-	return true
+func hashPassword(password string) ([]byte, error) {
+	defer metrics.ObservePasswordHash(metrics.HashGenerate, time.Now())
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 }
 
 func (u *User) CheckPassword(password string) bool {
+	defer metrics.ObservePasswordHash(metrics.HashCompare, time.Now())
 	err := bcrypt.CompareHashAndPassword(u.hash, []byte(password))
 	if err != nil {
 		return false
@@ -86,6 +87,20 @@ func (u *User) CheckPassword(password string) bool {
 	return true
 }
 
+// SetPassword validates password against the password policy and replaces
+// the stored hash.
+func (u *User) SetPassword(password string) error {
+	if err := validationError(passwordViolations("password", password)); err != nil {
+		return err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.hash = hash
+	return nil
+}
+
 func (u *User) Activate() {
 	u.Activated = true
 }
@@ -96,6 +111,12 @@ type CreationDTO struct {
 	Password string `json:"password"`
 }
 
+// UpdateDTO is a partial profile update; nil fields are left unchanged.
+type UpdateDTO struct {
+	Name  *string `json:"name,omitempty"`
+	Email *string `json:"email,omitempty"`
+}
+
 type DTO struct {
 	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
@@ -104,11 +125,70 @@ type DTO struct {
 	Joined    time.Time `json:"joined"`
 }
 
+type ListDTO struct {
+	Users      []DTO  `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+type SearchResultDTO struct {
+	User  DTO     `json:"user"`
+	Score float64 `json:"score"`
+}
+
+type SearchResultsDTO struct {
+	Results []SearchResultDTO `json:"results"`
+}
+
+type MeDTO struct {
+	DTO
+	Roles []string `json:"roles"`
+}
+
 type PasswordWrapper struct {
 	Identifier string `json:"identifier"`
 	Password   string `json:"password"`
 }
 
 type TokenWrapper struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type VerificationWrapper struct {
 	Token string `json:"token"`
 }
+
+type PasswordResetWrapper struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type PasswordChangeWrapper struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type EmailWrapper struct {
+	Email string `json:"email"`
+}
+
+type RefreshWrapper struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Introspection is the RFC 7662 introspection response. Inactive tokens only
+// carry Active.
+type Introspection struct {
+	Active    bool     `json:"active"`
+	Subject   string   `json:"sub,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Scope     string   `json:"scope,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Exp       int64    `json:"exp,omitempty"`
+	Iat       int64    `json:"iat,omitempty"`
+	Iss       string   `json:"iss,omitempty"`
+	Jti       string   `json:"jti,omitempty"`
+}
@@ -171,7 +171,7 @@ func TestUser_CheckPassword(t *testing.T) {
 	}
 }
 
-func Test_isPasswordValid(t *testing.T) {
+func Test_passwordViolations(t *testing.T) {
 	validPassword := "password"
 	passwordTooShort := "pw"
 	passwordContainsSpace := "password with space"
@@ -223,8 +223,8 @@ func Test_isPasswordValid(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isPasswordValid(tt.args.password); got != tt.want {
-				t.Errorf("isPasswordValid() = %v, want %v", got, tt.want)
+			if got := len(passwordViolations("password", tt.args.password)) == 0; got != tt.want {
+				t.Errorf("passwordViolations() empty = %v, want %v", got, tt.want)
 			}
 		})
 	}
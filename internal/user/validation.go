@@ -0,0 +1,107 @@
+package user
+
+import (
+	"awesomeProject/internal/apperror"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const minPasswordLength = 8
+
+// ValidationError lists every field of a request that failed validation. It
+// matches ErrInvalidEmail and ErrInvalidPassword with errors.Is when those
+// fields are among the violations.
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Violation is one problem with one field.
+type Violation struct {
+	// Field is the JSON name of the field.
+	Field string
+	// Code identifies the kind of problem for clients, and Params fill in
+	// its message.
+	Code    apperror.Code
+	Params  map[string]string
+	Message string
+	// Err is the domain error the violation corresponds to, if any.
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + " " + v.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	var errs []error
+	for _, v := range e.Violations {
+		if v.Err != nil {
+			errs = append(errs, v.Err)
+		}
+	}
+	return errs
+}
+
+// validationError returns nil when there are no violations.
+func validationError(violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// Validate reports every problem with dto at once as a *ValidationError.
+func (dto CreationDTO) Validate() error {
+	violations := nameViolations("name", dto.Name)
+	violations = append(violations, emailViolations("email", dto.Email)...)
+	violations = append(violations, passwordViolations("password", dto.Password)...)
+	return validationError(violations)
+}
+
+func nameViolations(field, name string) []Violation {
+	if strings.TrimSpace(name) == "" {
+		return []Violation{{Field: field, Code: apperror.CodeFieldRequired, Message: "must not be empty"}}
+	}
+	return nil
+}
+
+// requiredViolations reports a request field that was left empty.
+func requiredViolations(field, value string) []Violation {
+	if value == "" {
+		return []Violation{{Field: field, Code: apperror.CodeFieldRequired, Message: "must not be empty"}}
+	}
+	return nil
+}
+
+func emailViolations(field, email string) []Violation {
+	switch {
+	case email == "":
+		return []Violation{{Field: field, Code: apperror.CodeFieldRequired, Message: "must not be empty", Err: ErrInvalidEmail}}
+	case !isValidEmail(email):
+		return []Violation{{Field: field, Code: apperror.CodeFieldInvalidEmail, Message: "must be a valid email address", Err: ErrInvalidEmail}}
+	}
+	return nil
+}
+
+// passwordViolations checks password against the password policy.
+func passwordViolations(field, password string) []Violation {
+	var violations []Violation
+	if len(password) < minPasswordLength {
+		violations = append(violations, Violation{
+			Field:   field,
+			Code:    apperror.CodeFieldTooShort,
+			Params:  map[string]string{"min": strconv.Itoa(minPasswordLength)},
+			Message: fmt.Sprintf("must be at least %d characters", minPasswordLength),
+			Err:     ErrInvalidPassword,
+		})
+	}
+	if strings.Contains(password, " ") {
+		violations = append(violations, Violation{Field: field, Code: apperror.CodeFieldContainsSpace, Message: "must not contain spaces", Err: ErrInvalidPassword})
+	}
+	return violations
+}
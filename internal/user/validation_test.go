@@ -0,0 +1,32 @@
+package user
+
+import (
+	"awesomeProject/internal/apperror"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCreationDTO_Validate(t *testing.T) {
+	if err := (CreationDTO{Name: "valid", Email: "valid@email.test", Password: "password"}).Validate(); err != nil {
+		t.Fatalf("Validate() error = %v for a valid DTO", err)
+	}
+
+	err := CreationDTO{Name: " ", Email: "not-an-email", Password: "a b"}.Validate()
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Validate() error = %v, want a *ValidationError", err)
+	}
+	want := []Violation{
+		{Field: "name", Code: apperror.CodeFieldRequired, Message: "must not be empty"},
+		{Field: "email", Code: apperror.CodeFieldInvalidEmail, Message: "must be a valid email address", Err: ErrInvalidEmail},
+		{Field: "password", Code: apperror.CodeFieldTooShort, Params: map[string]string{"min": "8"}, Message: "must be at least 8 characters", Err: ErrInvalidPassword},
+		{Field: "password", Code: apperror.CodeFieldContainsSpace, Message: "must not contain spaces", Err: ErrInvalidPassword},
+	}
+	if !reflect.DeepEqual(verr.Violations, want) {
+		t.Errorf("Validate() violations = %+v, want %+v", verr.Violations, want)
+	}
+	if !errors.Is(err, ErrInvalidEmail) || !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("Validate() error = %v should match ErrInvalidEmail and ErrInvalidPassword", err)
+	}
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"awesomeProject/internal/mail"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// VerifyEmail consumes a verification token and activates its user.
+func (us *InMemoryService) VerifyEmail(ctx context.Context, token string) error {
+	t, err := us.actionTokens.GetByHash(ctx, PurposeVerifyEmail, hashToken(token))
+	if errors.Is(err, ErrTokenNotFound) {
+		return fmt.Errorf("%w: unknown verification token", ErrInvalidToken)
+	}
+	if err != nil {
+		return err
+	}
+	if t.Used() || t.Expired(time.Now()) {
+		return fmt.Errorf("%w: verification token is no longer valid", ErrInvalidToken)
+	}
+	ok, err := us.actionTokens.Use(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: verification token is no longer valid", ErrInvalidToken)
+	}
+	return us.users.Activate(ctx, t.UserID)
+}
+
+// ResendVerification mails a fresh verification token, invalidating earlier
+// ones. Unknown, already activated and disabled addresses are silently
+// ignored so the endpoint can't be used to probe for accounts; the token and
+// mail are done in the background for the same reason.
+func (us *InMemoryService) ResendVerification(ctx context.Context, email string) error {
+	u, err := us.users.GetByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if u.Activated || u.Disabled {
+		return nil
+	}
+	us.sendVerification(ctx, u)
+	return nil
+}
+
+// sendVerification mails u a verification token in the background, after
+// invalidating the ones mailed before.
+func (us *InMemoryService) sendVerification(ctx context.Context, u *User) {
+	us.sendInBackground(ctx, "email verification", u.ID, func(ctx context.Context) error {
+		return us.mailVerification(ctx, u)
+	})
+}
+
+func (us *InMemoryService) mailVerification(ctx context.Context, u *User) error {
+	if err := us.actionTokens.InvalidateForUser(ctx, u.ID, PurposeVerifyEmail); err != nil {
+		return err
+	}
+	t, value, err := newActionToken(u.ID, PurposeVerifyEmail, verificationTokenTTL)
+	if err != nil {
+		return err
+	}
+	if err := us.actionTokens.Add(ctx, t); err != nil {
+		return err
+	}
+	return us.mailer.Send(ctx, mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s%s\n\nThe link expires in %s.\n",
+			u.Name, us.verifyURL, value, verificationTokenTTL,
+		),
+	})
+}
@@ -0,0 +1,200 @@
+package user
+
+import (
+	"awesomeProject/internal/mail"
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outbox is a mail.Sender that keeps every message.
+type outbox struct {
+	// wait blocks until the mails sent in the background are delivered.
+	wait func()
+
+	mu       sync.Mutex
+	messages []mail.Message
+}
+
+func (o *outbox) Send(_ context.Context, m mail.Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, m)
+	return nil
+}
+
+// sent returns the messages delivered once background mails are done.
+func (o *outbox) sent() []mail.Message {
+	if o.wait != nil {
+		o.wait()
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]mail.Message(nil), o.messages...)
+}
+
+var mailedToken = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+// lastToken returns the token from the link in the latest mail.
+func (o *outbox) lastToken(t *testing.T) string {
+	t.Helper()
+	messages := o.sent()
+	if len(messages) == 0 {
+		t.Fatal("no mail was sent")
+	}
+	m := mailedToken.FindStringSubmatch(messages[len(messages)-1].Body)
+	if m == nil {
+		t.Fatalf("mail has no token link: %q", messages[len(messages)-1].Body)
+	}
+	return m[1]
+}
+
+func newVerificationTestService(t *testing.T, opts ...Option) (*InMemoryService, *outbox) {
+	t.Helper()
+	box := &outbox{}
+	users := &InMemStore{
+		usersByName:  map[string]*User{},
+		usersByID:    map[uuid.UUID]*User{},
+		usersByEmail: map[string]*User{},
+	}
+	opts = append([]Option{
+		WithMailer(box, "http://localhost/verify?token="),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+	us := newService(t, users, opts...)
+	box.wait = us.WaitForMails
+	return us, box
+}
+
+func TestInMemoryService_CreateActivatedUser(t *testing.T) {
+	us, box := newVerificationTestService(t)
+	u, err := us.CreateActivatedUser(t.Context(), uuid.Nil, "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatalf("CreateActivatedUser() error = %v", err)
+	}
+	if len(box.sent()) != 0 {
+		t.Errorf("CreateActivatedUser() sent %v, want no mail", box.sent())
+	}
+	got, err := us.GetUserByID(t.Context(), u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Activated {
+		t.Errorf("CreateActivatedUser() stored an inactive user")
+	}
+}
+
+func TestInMemoryService_VerifyEmail(t *testing.T) {
+	us, box := newVerificationTestService(t)
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(box.sent()) != 1 || box.sent()[0].To != u.Email {
+		t.Fatalf("CreateNewUser() sent %v, want one mail to %s", box.sent(), u.Email)
+	}
+	token := box.lastToken(t)
+
+	if err := us.VerifyEmail(t.Context(), "unknown"); err == nil {
+		t.Errorf("VerifyEmail() with an unknown token should fail")
+	}
+	if err := us.VerifyEmail(t.Context(), token); err != nil {
+		t.Fatalf("VerifyEmail() error = %v", err)
+	}
+	got, err := us.GetUserByID(t.Context(), u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Activated {
+		t.Errorf("VerifyEmail() did not activate the user")
+	}
+	if err := us.VerifyEmail(t.Context(), token); err == nil {
+		t.Errorf("VerifyEmail() should not accept a token twice")
+	}
+}
+
+func TestInMemoryService_VerifyEmail_Expired(t *testing.T) {
+	us, _ := newVerificationTestService(t)
+	u, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, value, err := newActionToken(u.ID, PurposeVerifyEmail, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := us.actionTokens.Add(t.Context(), tok); err != nil {
+		t.Fatal(err)
+	}
+	if err := us.VerifyEmail(t.Context(), value); err == nil {
+		t.Errorf("VerifyEmail() should reject an expired token")
+	}
+}
+
+func TestInMemoryService_ResendVerification(t *testing.T) {
+	us, box := newVerificationTestService(t)
+	if _, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+	first := box.lastToken(t)
+
+	if err := us.ResendVerification(t.Context(), "valid@email.test"); err != nil {
+		t.Fatalf("ResendVerification() error = %v", err)
+	}
+	second := box.lastToken(t)
+	if err := us.VerifyEmail(t.Context(), first); err == nil {
+		t.Errorf("VerifyEmail() accepted a token superseded by a resend")
+	}
+	if err := us.VerifyEmail(t.Context(), second); err != nil {
+		t.Errorf("VerifyEmail() error = %v", err)
+	}
+
+	sent := len(box.sent())
+	if err := us.ResendVerification(t.Context(), "valid@email.test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := us.ResendVerification(t.Context(), "unknown@email.test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(box.sent()) != sent {
+		t.Errorf("ResendVerification() mailed an activated or unknown address")
+	}
+
+	disabled, err := us.CreateNewUser(t.Context(), "disabled", "disabled@email.test", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := us.SetDisabled(t.Context(), uuid.Nil, disabled.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	sent = len(box.sent())
+	if err := us.ResendVerification(t.Context(), "disabled@email.test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(box.sent()) != sent {
+		t.Errorf("ResendVerification() mailed a disabled user")
+	}
+}
+
+func TestInMemoryService_Authenticate_RequireActivation(t *testing.T) {
+	us, box := newVerificationTestService(t, WithRequireActivation(true))
+	if _, err := us.CreateNewUser(t.Context(), "valid", "valid@email.test", "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); err == nil {
+		t.Errorf("Authenticate() should refuse a user that is not activated")
+	}
+	if err := us.VerifyEmail(t.Context(), box.lastToken(t)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := us.Authenticate(t.Context(), "valid@email.test", "password"); err != nil {
+		t.Errorf("Authenticate() error = %v after activation", err)
+	}
+}
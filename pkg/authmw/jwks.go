@@ -0,0 +1,289 @@
+package authmw
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultJWKSTTL       = 5 * time.Minute
+	minJWKSRefreshPeriod = 30 * time.Second
+	// maxJWKSBackoff caps the wait between fetches while they keep failing.
+	maxJWKSBackoff = 5 * time.Minute
+)
+
+// JWKS fetches and caches the auth service's published keys. Keys are
+// refetched when the cache is older than its TTL, or when a token names an
+// unknown kid (at most once per 30s), so rotated keys are picked up without a
+// restart. A failed fetch is retried after a growing backoff, and the cached
+// keys keep verifying in the meantime.
+//
+// Only one fetch runs at a time. Tokens with a cached kid never wait for it;
+// tokens with an unknown kid wait for its result.
+type JWKS struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]jwk
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// failures counts the fetches that failed since the last success, and
+	// fetchErr is the error of the latest one.
+	failures int
+	fetchErr error
+	// fetching is closed when the fetch in flight finishes. It is nil when
+	// no fetch is running.
+	fetching chan struct{}
+}
+
+type JWKSOption func(*JWKS)
+
+func WithHTTPClient(c *http.Client) JWKSOption {
+	return func(j *JWKS) {
+		j.client = c
+	}
+}
+
+func WithCacheTTL(ttl time.Duration) JWKSOption {
+	return func(j *JWKS) {
+		j.ttl = ttl
+	}
+}
+
+func NewJWKS(url string, opts ...JWKSOption) *JWKS {
+	j := &JWKS{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		ttl:    defaultJWKSTTL,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// NewJWKSVerifier verifies RS256, ES256 and EdDSA tokens against the key set
+// published at url.
+func NewJWKSVerifier(url string, opts ...VerifierOption) *JWTVerifier {
+	return NewJWKS(url).Verifier(opts...)
+}
+
+func (j *JWKS) Verifier(opts ...VerifierOption) *JWTVerifier {
+	methods := []string{
+		jwt.SigningMethodRS256.Alg(),
+		jwt.SigningMethodES256.Alg(),
+		jwt.SigningMethodEdDSA.Alg(),
+	}
+	v := NewKeyfuncVerifier(j.Keyfunc, methods, opts...)
+	v.keyfuncFor = j.KeyfuncContext
+	return v
+}
+
+// Keyfunc is KeyfuncContext without a caller context.
+func (j *JWKS) Keyfunc(t *jwt.Token) (any, error) {
+	return j.KeyfuncContext(context.Background())(t)
+}
+
+// KeyfuncContext returns a jwt.Keyfunc that stops waiting for a fetch of the
+// keys when ctx is done.
+func (j *JWKS) KeyfuncContext(ctx context.Context) jwt.Keyfunc {
+	return func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("token has no kid")
+		}
+		k, err := j.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if k.Alg != "" && k.Alg != t.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %q for key %q", t.Method.Alg(), kid)
+		}
+		return k.public, nil
+	}
+}
+
+func (j *JWKS) key(ctx context.Context, kid string) (jwk, error) {
+	j.mu.Lock()
+	k, ok := j.keys[kid]
+	done := j.fetching
+	if done == nil && j.due(time.Now(), ok) {
+		done = j.startFetch(ctx)
+	}
+	j.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return jwk{}, fmt.Errorf("%w: %w", ErrUnavailable, ctx.Err())
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if k, ok := j.keys[kid]; ok {
+		return k, nil
+	}
+	if j.fetchErr != nil {
+		return jwk{}, fmt.Errorf("%w: %w", ErrUnavailable, j.fetchErr)
+	}
+	return jwk{}, fmt.Errorf("unknown key %q", kid)
+}
+
+// due reports whether the keys should be fetched now, given whether the kid
+// in question is cached. j.mu must be held.
+func (j *JWKS) due(now time.Time, known bool) bool {
+	wait := minJWKSRefreshPeriod
+	if j.failures > 0 {
+		wait = min(minJWKSRefreshPeriod<<min(j.failures-1, 4), maxJWKSBackoff)
+	}
+	if !j.attemptedAt.IsZero() && now.Sub(j.attemptedAt) < wait {
+		return false
+	}
+	return !known || now.Sub(j.fetchedAt) >= j.ttl
+}
+
+// startFetch fetches the keys in the background and returns a channel that is
+// closed when it is done. The fetch outlives ctx, since other callers may be
+// waiting for it, but keeps its values. j.mu must be held.
+func (j *JWKS) startFetch(ctx context.Context) chan struct{} {
+	done := make(chan struct{})
+	j.fetching = done
+	j.attemptedAt = time.Now()
+	go func() {
+		defer close(done)
+		keys, err := j.fetch(context.WithoutCancel(ctx))
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.fetching = nil
+		if err != nil {
+			j.failures++
+			j.fetchErr = err
+			return
+		}
+		j.keys = keys
+		j.fetchedAt = time.Now()
+		j.failures = 0
+		j.fetchErr = nil
+	}()
+	return done
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]jwk, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]jwk, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if err := k.parse(); err != nil {
+			continue
+		}
+		keys[k.Kid] = k
+	}
+	return keys, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+
+	public any
+}
+
+func (k *jwk) parse() error {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64(k.N)
+		if err != nil {
+			return err
+		}
+		e, err := decodeBase64(k.E)
+		if err != nil {
+			return err
+		}
+		k.public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		if k.Crv != "P-256" {
+			return fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return err
+		}
+		y, err := decodeBase64(k.Y)
+		if err != nil {
+			return err
+		}
+		point := append([]byte{4}, append(x, y...)...)
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return err
+		}
+		k.public = pub
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return errors.New("invalid Ed25519 key size")
+		}
+		k.public = ed25519.PublicKey(x)
+	default:
+		return fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	return nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,151 @@
+package authmw
+
+import (
+	"awesomeProject/internal/signing"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWKSVerifier(t *testing.T) {
+	for _, alg := range []string{signing.RS256, signing.ES256, signing.EdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			key, err := signing.GenerateKey(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys := signing.NewStaticKeys(key)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				published, _ := keys.PublicKeys()
+				set, _ := signing.NewJWKSet(published)
+				json.NewEncoder(w).Encode(set)
+			}))
+			defer srv.Close()
+
+			token, err := signing.Sign(keys, validClaims())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewJWKSVerifier(srv.URL).Verify(context.Background(), token)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if got.Subject != "user-id" {
+				t.Errorf("Verify() sub = %v, want user-id", got.Subject)
+			}
+		})
+	}
+}
+
+func TestJWKSVerifier_RejectsHMACAndUnknownKeys(t *testing.T) {
+	key, err := signing.GenerateKey(signing.ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := signing.NewStaticKeys(key)
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		published, _ := keys.PublicKeys()
+		set, _ := signing.NewJWKSet(published)
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+	v := NewJWKSVerifier(srv.URL)
+
+	hmac := signHS256(t, "secret", validClaims())
+	if _, err := v.Verify(context.Background(), hmac); err == nil {
+		t.Errorf("Verify() should reject HS256 tokens")
+	}
+
+	other, err := signing.GenerateKey(signing.ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unknown, err := signing.Sign(signing.NewStaticKeys(other), validClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(context.Background(), unknown); err == nil {
+			t.Fatalf("Verify() should reject a token signed by an unpublished key")
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("unknown kids caused %d JWKS fetches, want 1", fetches.Load())
+	}
+}
+
+func TestJWKSVerifier_FetchFailureBacksOff(t *testing.T) {
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	v := NewJWKSVerifier(srv.URL)
+
+	key, err := signing.GenerateKey(signing.ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := signing.Sign(signing.NewStaticKeys(key), validClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(context.Background(), token); !errors.Is(err, ErrUnavailable) {
+			t.Fatalf("Verify() error = %v, want ErrUnavailable", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Errorf("failed fetches were retried %d times, want 1 within the backoff", fetches.Load())
+	}
+}
+
+func TestJWKSVerifier_ServesCachedKeysDuringRefresh(t *testing.T) {
+	key, err := signing.GenerateKey(signing.ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := signing.NewStaticKeys(key)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Every fetch after the first hangs until the test ends.
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		published, _ := keys.PublicKeys()
+		set, _ := signing.NewJWKSet(published)
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+	defer close(release)
+	v := NewJWKS(srv.URL, WithCacheTTL(time.Nanosecond)).Verifier()
+
+	token, err := signing.Sign(keys, validClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// The cache is stale now, but a verification must not wait for the
+	// hanging refresh.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := v.Verify(ctx, token); err != nil {
+		t.Fatalf("Verify() during a refresh error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Verify() waited %v for a refresh of cached keys", elapsed)
+	}
+}
@@ -0,0 +1,101 @@
+package authmw
+
+import (
+	"awesomeProject/internal/apperror"
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type contextKey struct{}
+
+// WithClaims returns a copy of ctx carrying claims. Middleware does this for
+// verified requests; tests can use it to fake an authenticated caller.
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// Subject returns the caller's user ID, or "" for unauthenticated requests.
+func Subject(ctx context.Context) string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.Subject
+	}
+	return ""
+}
+
+// Roles returns the caller's roles, or nil for unauthenticated requests.
+func Roles(ctx context.Context) []string {
+	if claims, ok := ClaimsFromContext(ctx); ok {
+		return claims.Roles
+	}
+	return nil
+}
+
+func HasRole(ctx context.Context, role string) bool {
+	return slices.Contains(Roles(ctx), role)
+}
+
+// Middleware rejects requests without a valid bearer token with 401 and
+// stores the verified claims in the request context.
+func Middleware(v Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				unauthorized(w, r, errors.New("bearer token must be provided"))
+				return
+			}
+			claims, err := v.Verify(r.Context(), token)
+			if errors.Is(err, ErrUnavailable) {
+				unavailable(w, r, err)
+				return
+			}
+			if err != nil {
+				unauthorized(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// RequireRole only lets callers holding role through; others get 403.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return RequireAnyRole(role)
+}
+
+// RequireAnyRole lets callers holding at least one of roles through; others
+// get 403. It must run after Middleware.
+func RequireAnyRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := ClaimsFromContext(r.Context()); !ok {
+				unauthorized(w, r, errors.New("authentication required"))
+				return
+			}
+			for _, role := range roles {
+				if HasRole(r.Context(), role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			apperror.Write(w, r, apperror.Forbidden(errors.New("insufficient role")).WithCode(apperror.CodeAuthInsufficientRole))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	apperror.Write(w, r, apperror.Unauthorized(err).WithCode(apperror.CodeAuthInvalidToken))
+}
+
+func unavailable(w http.ResponseWriter, r *http.Request, err error) {
+	apperror.Write(w, r, apperror.NewHTTPErrorWithMessage(err, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)).WithCode(apperror.CodeServiceUnavailable))
+}
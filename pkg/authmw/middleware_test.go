@@ -0,0 +1,111 @@
+package authmw
+
+import (
+	"awesomeProject/internal/apperror"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	v := NewHMACVerifier([]byte("secret"))
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{name: "valid token", header: "Bearer " + signHS256(t, "secret", validClaims()), wantStatus: http.StatusOK},
+		{name: "invalid token", header: "Bearer " + signHS256(t, "other", validClaims()), wantStatus: http.StatusUnauthorized},
+		{name: "missing header", header: "", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", header: "Basic dXNlcjpwYXNz", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSubject string
+			var gotRoles []string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotSubject = Subject(r.Context())
+				gotRoles = Roles(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			Middleware(v)(next).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "user-id", gotSubject)
+				assert.Equal(t, []string{"admin"}, gotRoles)
+			} else {
+				assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+				assert.Equal(t, apperror.ContentType, w.Header().Get("Content-Type"))
+				var body apperror.Problem
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+				assert.NotEmpty(t, body.Detail)
+			}
+		})
+	}
+}
+
+// unavailableVerifier fails every token as a verifier that can't reach its
+// keys.
+type unavailableVerifier struct{}
+
+func (unavailableVerifier) Verify(context.Context, string) (*Claims, error) {
+	return nil, fmt.Errorf("%w: keys could not be fetched", ErrUnavailable)
+}
+
+func TestMiddleware_Unavailable(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+
+	Middleware(unavailableVerifier{})(next).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Empty(t, w.Header().Get("WWW-Authenticate"))
+}
+
+func TestRequireAnyRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		claims     *Claims
+		guard      func(http.Handler) http.Handler
+		wantStatus int
+	}{
+		{name: "has role", claims: &Claims{Roles: []string{"admin"}}, guard: RequireRole("admin"), wantStatus: http.StatusOK},
+		{name: "missing role", claims: &Claims{Roles: []string{"user"}}, guard: RequireRole("admin"), wantStatus: http.StatusForbidden},
+		{name: "has one of roles", claims: &Claims{Roles: []string{"support"}}, guard: RequireAnyRole("admin", "support"), wantStatus: http.StatusOK},
+		{name: "none of roles", claims: &Claims{Roles: nil}, guard: RequireAnyRole("admin", "support"), wantStatus: http.StatusForbidden},
+		{name: "unauthenticated", claims: nil, guard: RequireRole("admin"), wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.claims != nil {
+				req = req.WithContext(WithClaims(req.Context(), tt.claims))
+			}
+			w := httptest.NewRecorder()
+
+			tt.guard(next).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package authmw verifies access tokens issued by the auth service and makes
+// the caller's identity available to HTTP handlers.
+//
+// A consuming service wires it into chi like this:
+//
+//	v := authmw.NewJWKSVerifier("https://auth.example.com/.well-known/jwks.json")
+//	r.Use(authmw.Middleware(v))
+//	r.With(authmw.RequireRole("admin")).Delete("/things/{id}", deleteThing)
+package authmw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultIssuer is the iss claim set by the auth service.
+const DefaultIssuer = "auth-service"
+
+// Claims mirrors the claims of the auth service's access tokens.
+type Claims struct {
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// ErrUnavailable is matched by Verifier errors that mean the token couldn't
+// be checked, for example because the keys couldn't be fetched, rather than
+// that it is invalid. Middleware answers them with 503 instead of 401.
+var ErrUnavailable = errors.New("token verification unavailable")
+
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+// JWTVerifier checks a token's signature, expiry and issuer.
+type JWTVerifier struct {
+	keyfunc jwt.Keyfunc
+	// keyfuncFor, if set, replaces keyfunc with one bound to the context of
+	// the Verify call.
+	keyfuncFor func(context.Context) jwt.Keyfunc
+	methods    []string
+	issuer     string
+	leeway     time.Duration
+}
+
+type VerifierOption func(*JWTVerifier)
+
+// WithIssuer overrides the expected iss claim. Defaults to DefaultIssuer.
+func WithIssuer(issuer string) VerifierOption {
+	return func(v *JWTVerifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithLeeway tolerates clock skew when checking exp, nbf and iat.
+func WithLeeway(leeway time.Duration) VerifierOption {
+	return func(v *JWTVerifier) {
+		v.leeway = leeway
+	}
+}
+
+// NewHMACVerifier verifies HS256 tokens signed with the shared secret.
+func NewHMACVerifier(secret []byte, opts ...VerifierOption) *JWTVerifier {
+	keyfunc := func(*jwt.Token) (any, error) {
+		return secret, nil
+	}
+	return NewKeyfuncVerifier(keyfunc, []string{jwt.SigningMethodHS256.Alg()}, opts...)
+}
+
+// NewKeyfuncVerifier verifies tokens with a custom key lookup, restricted to
+// the given signing methods.
+func NewKeyfuncVerifier(keyfunc jwt.Keyfunc, methods []string, opts ...VerifierOption) *JWTVerifier {
+	v := &JWTVerifier{
+		keyfunc: keyfunc,
+		methods: methods,
+		issuer:  DefaultIssuer,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	if token == "" {
+		return nil, errors.New("token is empty")
+	}
+	keyfunc := v.keyfunc
+	if v.keyfuncFor != nil {
+		keyfunc = v.keyfuncFor(ctx)
+	}
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(
+		token,
+		claims,
+		keyfunc,
+		jwt.WithValidMethods(v.methods),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.leeway),
+	)
+	if errors.Is(err, ErrUnavailable) {
+		return nil, fmt.Errorf("failed to verify token: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid token: missing subject")
+	}
+	return claims, nil
+}
@@ -0,0 +1,83 @@
+package authmw
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signHS256(t *testing.T, secret string, claims Claims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() Claims {
+	return Claims{
+		Roles: []string{"admin"},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-id",
+			Issuer:    DefaultIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	v := NewHMACVerifier([]byte("secret"))
+
+	expired := validClaims()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	otherIssuer := validClaims()
+	otherIssuer.Issuer = "someone-else"
+	noExpiry := validClaims()
+	noExpiry.ExpiresAt = nil
+	noSubject := validClaims()
+	noSubject.Subject = ""
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid token", token: signHS256(t, "secret", validClaims()), wantErr: false},
+		{name: "wrong secret", token: signHS256(t, "other", validClaims()), wantErr: true},
+		{name: "expired", token: signHS256(t, "secret", expired), wantErr: true},
+		{name: "other issuer", token: signHS256(t, "secret", otherIssuer), wantErr: true},
+		{name: "no expiry", token: signHS256(t, "secret", noExpiry), wantErr: true},
+		{name: "no subject", token: signHS256(t, "secret", noSubject), wantErr: true},
+		{name: "empty", token: "", wantErr: true},
+		{name: "garbage", token: "not.a.token", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got.Subject != "user-id" || len(got.Roles) != 1) {
+				t.Errorf("Verify() = %+v, want subject and roles", got)
+			}
+		})
+	}
+}
+
+func TestJWTVerifier_WithIssuerAndLeeway(t *testing.T) {
+	claims := validClaims()
+	claims.Issuer = "custom"
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+	token := signHS256(t, "secret", claims)
+
+	if _, err := NewHMACVerifier([]byte("secret"), WithIssuer("custom")).Verify(context.Background(), token); err == nil {
+		t.Errorf("Verify() should reject an expired token without leeway")
+	}
+	if _, err := NewHMACVerifier([]byte("secret"), WithIssuer("custom"), WithLeeway(time.Minute)).Verify(context.Background(), token); err != nil {
+		t.Errorf("Verify() error = %v, want token accepted within leeway", err)
+	}
+}